@@ -0,0 +1,167 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wojnosystems/retry/retrytest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// attemptSeen is what the function being retried saw of its attempt
+type attemptSeen struct {
+	attempt   uint
+	elapsed   time.Duration
+	previous  string
+	remaining uint
+}
+
+func TestAttemptInfo(t *testing.T) {
+	cases := map[string]struct {
+		svc      func(clock Clock) Service
+		expected []attemptSeen
+	}{
+		"max attempts": {
+			svc: func(clock Clock) Service {
+				return MaxAttempts{Times: 3, WaitFor: time.Second, Clock: clock}.New()
+			},
+			expected: []attemptSeen{
+				{attempt: 1, remaining: 2},
+				{attempt: 2, elapsed: 2 * time.Second, previous: "1", remaining: 1},
+				{attempt: 3, elapsed: 4 * time.Second, previous: "2", remaining: 0},
+			},
+		},
+		"unlimited": {
+			svc: func(clock Clock) Service {
+				return Linear{Times: Unlimited, Initial: time.Second, Clock: clock}.New()
+			},
+			expected: []attemptSeen{
+				{attempt: 1, remaining: Unlimited},
+				{attempt: 2, elapsed: 2 * time.Second, previous: "1", remaining: Unlimited},
+			},
+		},
+		"circuit breaker": {
+			svc: func(clock Clock) Service {
+				breaker := &CircuitBreaker{ConsecutiveFailures: 10, CoolDown: time.Minute, Clock: clock}
+				return breaker.Wrap(MaxAttempts{Times: 2, WaitFor: time.Second, Clock: clock}.New())
+			},
+			expected: []attemptSeen{
+				{attempt: 1, remaining: 1},
+				{attempt: 2, elapsed: 2 * time.Second, previous: "1", remaining: 0},
+			},
+		},
+		"all": {
+			svc: func(clock Clock) Service {
+				return All(
+					MaxAttempts{Times: 2, WaitFor: time.Second, Clock: clock}.New(),
+					MaxAttempts{Times: 5, WaitFor: time.Second, Clock: clock}.New(),
+				)
+			},
+			expected: []attemptSeen{
+				{attempt: 1, remaining: 1},
+				{attempt: 2, elapsed: 2 * time.Second, previous: "1", remaining: 0},
+			},
+		},
+		"any": {
+			svc: func(clock Clock) Service {
+				return Any(
+					MaxAttempts{Times: 1, WaitFor: time.Second, Clock: clock}.New(),
+					MaxAttempts{Times: 2, WaitFor: time.Second, Clock: clock}.New(),
+				)
+			},
+			expected: []attemptSeen{
+				{attempt: 1, remaining: 1},
+				{attempt: 2, elapsed: 2 * time.Second, previous: "1", remaining: 0},
+			},
+		},
+		"sequence": {
+			svc: func(clock Clock) Service {
+				return Sequence(
+					MaxAttempts{Times: 2, WaitFor: time.Second, Clock: clock}.New(),
+					MaxAttempts{Times: 2, WaitFor: time.Second, Clock: clock}.New(),
+				)
+			},
+			expected: []attemptSeen{
+				{attempt: 1, remaining: 3},
+				{attempt: 2, elapsed: 2 * time.Second, previous: "1", remaining: 2},
+				{attempt: 3, elapsed: 4 * time.Second, previous: "2", remaining: 1},
+				{attempt: 4, elapsed: 6 * time.Second, previous: "3", remaining: 0},
+			},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			clock := retrytest.NewAutoClock(time.Now())
+			var seen []attemptSeen
+			_ = How(c.svc(clock)).This(func(controller ServiceController) error {
+				info := controller.(AttemptInfo)
+				s := attemptSeen{attempt: info.Attempt(), elapsed: info.Elapsed(), remaining: info.Remaining()}
+				if info.PreviousError() != nil {
+					s.previous = info.PreviousError().Error()
+				}
+				seen = append(seen, s)
+				clock.Advance(time.Second)
+				if len(seen) == len(c.expected) {
+					controller.Abort()
+				}
+				return fmt.Errorf("%d", len(seen))
+			})
+			if len(seen) != len(c.expected) {
+				t.Fatalf(`expected %d attempts, but got %d`, len(c.expected), len(seen))
+			}
+			for i := range c.expected {
+				if seen[i] != c.expected[i] {
+					t.Errorf(`attempt %d: expected %+v but got %+v`, i+1, c.expected[i], seen[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAttemptInfo_Hedge(t *testing.T) {
+	var mu sync.Mutex
+	var seen []attemptSeen
+	_ = Hedge{Attempts: 3, Delay: time.Hour}.This(func(controller ServiceController) error {
+		info := controller.(AttemptInfo)
+		s := attemptSeen{attempt: info.Attempt(), remaining: info.Remaining()}
+		if info.PreviousError() != nil {
+			s.previous = info.PreviousError().Error()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, s)
+		return errors.New("boom")
+	})
+
+	expected := []attemptSeen{
+		{attempt: 1, remaining: 2},
+		{attempt: 2, previous: "boom", remaining: 1},
+		{attempt: 3, previous: "boom", remaining: 0},
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != len(expected) {
+		t.Fatalf(`expected %d attempts, but got %d`, len(expected), len(seen))
+	}
+	for i := range expected {
+		if seen[i] != expected[i] {
+			t.Errorf(`attempt %d: expected %+v but got %+v`, i+1, expected[i], seen[i])
+		}
+	}
+}
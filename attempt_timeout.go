@@ -0,0 +1,67 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrAttemptTimeout is matched by errors.Is when an attempt ran out of its AttemptTimeout
+var ErrAttemptTimeout = errors.New("attempt timed out")
+
+// AttemptTimeoutError is recorded in place of the error returned by an attempt that ran past its timeout
+type AttemptTimeoutError struct {
+	// Timeout is how long the attempt was allowed to run
+	Timeout time.Duration
+
+	// Err is the error the attempt returned
+	Err error
+}
+
+func (e *AttemptTimeoutError) Error() string {
+	return fmt.Sprintf("attempt timed out after %v: %v", e.Timeout, e.Err)
+}
+
+// Unwrap returns the error the attempt returned
+func (e *AttemptTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Is allows errors.Is(err, ErrAttemptTimeout) to match
+func (e *AttemptTimeoutError) Is(target error) bool {
+	return target == ErrAttemptTimeout
+}
+
+// attemptTimeout calculates the timeout for the attempt after triesSoFar attempts: timeout * growth^triesSoFar.
+// A growth of 0 is treated as 1 (no growth). The result saturates rather than overflowing.
+func attemptTimeout(timeout time.Duration, growth float64, triesSoFar uint) time.Duration {
+	if timeout <= 0 {
+		return 0
+	}
+	if growth == 0 || growth == 1 {
+		return timeout
+	}
+	scaled := float64(timeout) * math.Pow(growth, float64(triesSoFar))
+	if scaled >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if scaled < 1 {
+		return 1
+	}
+	return time.Duration(scaled)
+}
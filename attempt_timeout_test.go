@@ -0,0 +1,112 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestAttemptTimeout tests the growth of the per-attempt timeout
+func TestAttemptTimeout(t *testing.T) {
+	cases := map[string]struct {
+		cfg      MaxAttempts
+		expected []time.Duration
+	}{
+		"no timeout": {
+			cfg:      MaxAttempts{},
+			expected: []time.Duration{0, 0, 0},
+		},
+		"constant": {
+			cfg:      MaxAttempts{AttemptTimeout: 1 * time.Second},
+			expected: []time.Duration{1 * time.Second, 1 * time.Second, 1 * time.Second},
+		},
+		"doubling": {
+			cfg:      MaxAttempts{AttemptTimeout: 1 * time.Second, AttemptTimeoutGrowth: 2},
+			expected: []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		"fractional": {
+			cfg:      MaxAttempts{AttemptTimeout: 10 * time.Second, AttemptTimeoutGrowth: 1.5},
+			expected: []time.Duration{10 * time.Second, 15 * time.Second, 22500 * time.Millisecond},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			svc := c.cfg.New().(*maxExponentialService)
+			for i := range c.expected {
+				actual := svc.AttemptTimeout()
+				if actual != c.expected[i] {
+					t.Errorf(`expected timeout: %v but got %v`, c.expected[i], actual)
+				}
+				svc.NotifyRetry()
+			}
+		})
+	}
+}
+
+// TestAttemptTimeout_Saturates ensures that a growing timeout never overflows
+func TestAttemptTimeout_Saturates(t *testing.T) {
+	actual := attemptTimeout(1*time.Hour, 10, 100)
+	if actual != math.MaxInt64 {
+		t.Errorf(`expected timeout: %v but got %v`, time.Duration(math.MaxInt64), actual)
+	}
+}
+
+// TestRetry_AttemptTimeout ensures that each attempt gets its own deadline and that running out of it is recorded
+func TestRetry_AttemptTimeout(t *testing.T) {
+	attempts := 0
+	errList := How(MaxAttempts{
+		Times:          2,
+		AttemptTimeout: 1 * time.Millisecond,
+	}.New()).ThisContext(context.Background(), func(ctx context.Context, controller ServiceController) error {
+		attempts++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if attempts != 2 {
+		t.Errorf(`expected 2 attempts, got: %d`, attempts)
+	}
+	if errList == nil {
+		t.Fatal("expected an error list")
+	}
+	for _, err := range errList.Errors() {
+		if !errors.Is(err, ErrAttemptTimeout) {
+			t.Errorf(`expected an attempt timeout error, got: "%v"`, err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf(`expected the attempt's own error to be kept, got: "%v"`, err)
+		}
+	}
+}
+
+// TestRetry_AttemptTimeout_Success ensures that attempts finishing in time are unaffected by the timeout
+func TestRetry_AttemptTimeout_Success(t *testing.T) {
+	errList := How(MaxAttempts{
+		Times:          2,
+		AttemptTimeout: 1 * time.Minute,
+	}.New()).ThisContext(context.Background(), func(ctx context.Context, controller ServiceController) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the attempt's context to have a deadline")
+		}
+		return nil
+	})
+	if errList != nil {
+		t.Errorf(`expected no errors, got: "%v"`, errList)
+	}
+}
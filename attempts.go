@@ -0,0 +1,144 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import "time"
+
+// Attempt describes one try of the function being retried
+type Attempt struct {
+	// Number is the attempt's number, starting at 1
+	Number uint
+
+	// Start is when the attempt began
+	Start time.Time
+
+	// Duration is how long the attempt ran
+	Duration time.Duration
+
+	// Err is the error the attempt returned
+	Err error
+
+	// Wait is how long was spent in Yield after the attempt, 0 if there was no next attempt
+	Wait time.Duration
+}
+
+// AttemptHistory is implemented by the Errorer returned by a Retrier made with How, unless the Service made its own
+// error list without it. Attempts that were refused by an AttemptGate or never made because a context was done are
+// not included, but their errors are.
+type AttemptHistory interface {
+	// Attempts returns each attempt that was kept, in order. Bounded error lists keep attempts the same way they keep
+	// errors.
+	Attempts() []Attempt
+
+	// ExecutionTime is the total time spent in every attempt, including those that were not kept
+	ExecutionTime() time.Duration
+
+	// YieldTime is the total time spent waiting between attempts
+	YieldTime() time.Duration
+}
+
+// AttemptRecorder is optionally implemented by an ErrorAppender to be told about each failed attempt, after its
+// error is appended. Implement it, along with AttemptHistory, to record attempts in your own error list.
+type AttemptRecorder interface {
+	// RecordAttempt is called after each failed attempt
+	RecordAttempt(attempt Attempt)
+
+	// RecordWait is called with how long was spent waiting after the latest attempt
+	RecordWait(wait time.Duration)
+}
+
+// attemptLog records attempts for the built-in error lists
+type attemptLog struct {
+	kept firstLast[Attempt]
+	// latest is the latest attempt, if it was kept
+	latest    *Attempt
+	executing time.Duration
+	yielding  time.Duration
+}
+
+// record adds an attempt to the totals, and keeps it if keep is true and there is room
+func (l *attemptLog) record(attempt Attempt, keep bool) {
+	l.executing += attempt.Duration
+	l.latest = nil
+	if keep {
+		l.latest = l.kept.add(attempt)
+	}
+}
+
+func (l *attemptLog) RecordAttempt(attempt Attempt) {
+	l.record(attempt, true)
+}
+
+func (l *attemptLog) RecordWait(wait time.Duration) {
+	l.yielding += wait
+	if l.latest != nil {
+		l.latest.Wait = wait
+	}
+}
+
+func (l *attemptLog) Attempts() []Attempt {
+	return l.kept.all()
+}
+
+func (l *attemptLog) ExecutionTime() time.Duration {
+	return l.executing
+}
+
+func (l *attemptLog) YieldTime() time.Duration {
+	return l.yielding
+}
+
+// firstLast keeps the first values, and the last values in a ring, dropping those in between
+type firstLast[T any] struct {
+	first uint
+	last  uint
+	head  []T
+	// ring holds the latest values, the oldest of which is at next once it is full
+	ring []T
+	next int
+}
+
+// add keeps value, if there is room for it, and returns where it was kept until the next add. Returns nil if value
+// was dropped.
+func (f *firstLast[T]) add(value T) *T {
+	if uint(len(f.head)) < f.first {
+		f.head = append(f.head, value)
+		return &f.head[len(f.head)-1]
+	}
+	if f.last == 0 {
+		return nil
+	}
+	if uint(len(f.ring)) < f.last {
+		f.ring = append(f.ring, value)
+		return &f.ring[len(f.ring)-1]
+	}
+	kept := &f.ring[f.next]
+	*kept = value
+	f.next = (f.next + 1) % len(f.ring)
+	return kept
+}
+
+// all returns every kept value, in the order they were added
+func (f *firstLast[T]) all() []T {
+	kept := make([]T, 0, len(f.head)+len(f.ring))
+	kept = append(kept, f.head...)
+	kept = append(kept, f.ring[f.next:]...)
+	return append(kept, f.ring[:f.next]...)
+}
+
+// full is true once adding another value will drop one
+func (f *firstLast[T]) full() bool {
+	return uint(len(f.head)) >= f.first && uint(len(f.ring)) >= f.last
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wojnosystems/retry/retrytest"
+	"testing"
+	"time"
+)
+
+func TestAttemptHistory(t *testing.T) {
+	start := time.Now()
+	clock := retrytest.NewAutoClock(start)
+	errList := How(ExpBase2{
+		Times:   3,
+		Scaling: time.Second,
+		Clock:   clock,
+	}.New()).This(func(controller ServiceController) error {
+		clock.Advance(2 * time.Second)
+		return errors.New("boom")
+	})
+
+	history := errList.(AttemptHistory)
+	expected := []Attempt{
+		{Number: 1, Start: start, Duration: 2 * time.Second, Wait: 1 * time.Second},
+		{Number: 2, Start: start.Add(3 * time.Second), Duration: 2 * time.Second, Wait: 2 * time.Second},
+		{Number: 3, Start: start.Add(7 * time.Second), Duration: 2 * time.Second},
+	}
+	actual := history.Attempts()
+	if len(actual) != len(expected) {
+		t.Fatalf(`expected %d attempts, but got %d`, len(expected), len(actual))
+	}
+	for i := range expected {
+		if actual[i].Number != expected[i].Number || !actual[i].Start.Equal(expected[i].Start) || actual[i].Duration != expected[i].Duration || actual[i].Wait != expected[i].Wait {
+			t.Errorf(`attempt %d: expected %+v but got %+v`, i+1, expected[i], actual[i])
+		}
+		if actual[i].Err != errList.Errors()[i] {
+			t.Errorf(`attempt %d: expected the recorded error, but got %v`, i+1, actual[i].Err)
+		}
+	}
+	if history.ExecutionTime() != 6*time.Second {
+		t.Errorf(`expected execution time: %v but got %v`, 6*time.Second, history.ExecutionTime())
+	}
+	if history.YieldTime() != 3*time.Second {
+		t.Errorf(`expected yield time: %v but got %v`, 3*time.Second, history.YieldTime())
+	}
+}
+
+func TestAttemptHistory_Bounded(t *testing.T) {
+	cases := map[string]struct {
+		errorList ErrorListFactory
+		// repeats makes the messages repeat after this many attempts, 0 to never repeat
+		repeats int
+		kept    []string
+	}{
+		"ring": {
+			errorList: RingErrors{Size: 2},
+			kept:      []string{"3", "4"},
+		},
+		"first and last": {
+			errorList: FirstLastErrors{First: 1, Last: 1},
+			kept:      []string{"0", "4"},
+		},
+		"count": {
+			errorList: CountErrors{},
+			repeats:   2,
+			kept:      []string{"0", "1"},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			clock := retrytest.NewAutoClock(time.Now())
+			attempt := 0
+			errList := How(MaxAttempts{
+				Times:     5,
+				WaitFor:   time.Second,
+				Clock:     clock,
+				ErrorList: c.errorList,
+			}.New()).This(func(controller ServiceController) error {
+				clock.Advance(time.Second)
+				message := fmt.Sprint(attempt)
+				if c.repeats > 0 {
+					message = fmt.Sprint(attempt % c.repeats)
+				}
+				attempt++
+				return errors.New(message)
+			})
+
+			history := errList.(AttemptHistory)
+			actual := history.Attempts()
+			if len(actual) != len(c.kept) {
+				t.Fatalf(`expected %d attempts kept, but got %d`, len(c.kept), len(actual))
+			}
+			for i := range c.kept {
+				if actual[i].Err.Error() != c.kept[i] {
+					t.Errorf(`attempt %d: expected "%s" but got "%s"`, i, c.kept[i], actual[i].Err.Error())
+				}
+			}
+			if history.ExecutionTime() != 5*time.Second {
+				t.Errorf(`expected execution time: %v but got %v`, 5*time.Second, history.ExecutionTime())
+			}
+			if history.YieldTime() != 4*time.Second {
+				t.Errorf(`expected yield time: %v but got %v`, 4*time.Second, history.YieldTime())
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// These error lists keep a bounded number of errors, so a loop that retries for days does not keep every error in
+// memory. Set one as the ErrorList of a Service's configuration. Use Dropped to find out how many were not kept.
+
+// ErrorListFactory creates a new, empty error list for each thing to retry
+type ErrorListFactory interface {
+	New() ErrorAppender
+}
+
+// ErrorDropper is implemented by error lists that do not keep every error
+type ErrorDropper interface {
+	// Dropped returns how many errors were appended but not kept
+	Dropped() uint
+}
+
+// Dropped returns how many errors err did not keep, or 0 if it keeps them all. Returns 0 if err is nil.
+func Dropped(err Errorer) uint {
+	if dropper, ok := err.(ErrorDropper); ok {
+		return dropper.Dropped()
+	}
+	return 0
+}
+
+// FirstLastErrors keeps the First errors and the Last errors, dropping those in between
+type FirstLastErrors struct {
+	// First is how many of the earliest errors to keep
+	First uint
+
+	// Last is how many of the latest errors to keep
+	Last uint
+}
+
+// New creates an empty list
+func (l FirstLastErrors) New() ErrorAppender {
+	return &firstLastErrorList{
+		boundedErrorList: boundedErrorList{
+			attemptLog: attemptLog{kept: firstLast[Attempt]{first: l.First, last: l.Last}},
+		},
+		kept: firstLast[error]{first: l.First, last: l.Last},
+	}
+}
+
+// RingErrors keeps only the latest Size errors
+type RingErrors struct {
+	// Size is how many of the latest errors to keep
+	Size uint
+}
+
+// New creates an empty list
+func (l RingErrors) New() ErrorAppender {
+	return FirstLastErrors{Last: l.Size}.New()
+}
+
+// CountErrors keeps the first error with each distinct message, and counts how many times each message occurred
+type CountErrors struct{}
+
+// New creates an empty list
+func (l CountErrors) New() ErrorAppender {
+	return &countErrorList{
+		boundedErrorList: boundedErrorList{
+			attemptLog: attemptLog{kept: firstLast[Attempt]{first: Unlimited}},
+		},
+		indexes: make(map[string]int),
+	}
+}
+
+// ErrorCounter is implemented by the error list made by CountErrors
+type ErrorCounter interface {
+	// Counts returns each distinct error message in the order they first occurred
+	Counts() []ErrorCount
+}
+
+// ErrorCount is how many times an error message occurred
+type ErrorCount struct {
+	// Err is the first error with this message
+	Err error
+
+	// Count is how many errors had this message
+	Count uint
+}
+
+// boundedErrorList holds what every bounded list keeps, regardless of which errors it drops
+type boundedErrorList struct {
+	attemptLog
+	last    error
+	dropped uint
+	reason  error
+}
+
+// Last gets the final error returned by the retry, even if it was not kept
+func (e *boundedErrorList) Last() error {
+	return e.last
+}
+
+// Reason returns why retrying stopped
+func (e *boundedErrorList) Reason() error {
+	return e.reason
+}
+
+// SetReason records why retrying stopped
+func (e *boundedErrorList) SetReason(reason error) {
+	e.reason = reason
+}
+
+// Dropped returns how many errors were appended but not kept
+func (e *boundedErrorList) Dropped() uint {
+	return e.dropped
+}
+
+// unwrap returns kept, followed by the reason
+func (e *boundedErrorList) unwrap(kept []error) []error {
+	unwrapped := make([]error, len(kept), len(kept)+1)
+	copy(unwrapped, kept)
+	if e.reason == nil {
+		return unwrapped
+	}
+	return append(unwrapped, e.reason)
+}
+
+// firstLastErrorList keeps the earliest errors, and the latest in a ring
+type firstLastErrorList struct {
+	boundedErrorList
+	kept firstLast[error]
+}
+
+func (e *firstLastErrorList) Errors() []error {
+	return e.kept.all()
+}
+
+// Error is a default message. Override to make your own.
+func (e *firstLastErrorList) Error() string {
+	kept := e.kept.all()
+	parts := make([]string, 0, len(kept)+1)
+	for _, err := range kept[:len(e.kept.head)] {
+		parts = append(parts, err.Error())
+	}
+	if e.dropped > 0 {
+		parts = append(parts, fmt.Sprintf("(%d dropped)", e.dropped))
+	}
+	for _, err := range kept[len(e.kept.head):] {
+		parts = append(parts, err.Error())
+	}
+	return "retries exceeded; encountered errors: " + strings.Join(parts, ", ")
+}
+
+func (e *firstLastErrorList) Unwrap() []error {
+	return e.unwrap(e.Errors())
+}
+
+// Append keeps err if it is one of the first, otherwise it replaces the oldest of the latest
+func (e *firstLastErrorList) Append(err error) {
+	e.last = err
+	if e.kept.full() {
+		e.dropped++
+	}
+	e.kept.add(err)
+}
+
+// countErrorList keeps the first error with each message
+type countErrorList struct {
+	boundedErrorList
+	counts []ErrorCount
+	// indexes finds the count of a message
+	indexes map[string]int
+	// repeated is true if the latest error's message had been seen before
+	repeated bool
+}
+
+func (e *countErrorList) Errors() []error {
+	kept := make([]error, len(e.counts))
+	for i, count := range e.counts {
+		kept[i] = count.Err
+	}
+	return kept
+}
+
+// Error is a default message. Override to make your own.
+func (e *countErrorList) Error() string {
+	parts := make([]string, len(e.counts))
+	for i, count := range e.counts {
+		parts[i] = fmt.Sprintf("%s (x%d)", count.Err.Error(), count.Count)
+	}
+	return "retries exceeded; encountered errors: " + strings.Join(parts, ", ")
+}
+
+func (e *countErrorList) Unwrap() []error {
+	return e.unwrap(e.Errors())
+}
+
+// Append counts err, keeping it only if its message has not been seen before
+func (e *countErrorList) Append(err error) {
+	e.last = err
+	message := err.Error()
+	e.repeated = false
+	if i, ok := e.indexes[message]; ok {
+		e.counts[i].Count++
+		e.dropped++
+		e.repeated = true
+		return
+	}
+	e.indexes[message] = len(e.counts)
+	e.counts = append(e.counts, ErrorCount{Err: err, Count: 1})
+}
+
+// RecordAttempt keeps the attempt only if its error was kept
+func (e *countErrorList) RecordAttempt(attempt Attempt) {
+	e.record(attempt, !e.repeated)
+}
+
+// Counts returns each distinct error message in the order they first occurred
+func (e *countErrorList) Counts() []ErrorCount {
+	return append([]ErrorCount(nil), e.counts...)
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wojnosystems/retry/retrytest"
+	"testing"
+	"time"
+)
+
+func TestBoundedErrorLists(t *testing.T) {
+	cases := map[string]struct {
+		factory  ErrorListFactory
+		appended []string
+		kept     []string
+		dropped  uint
+	}{
+		"first and last": {
+			factory:  FirstLastErrors{First: 2, Last: 2},
+			appended: []string{"1", "2", "3", "4", "5", "6"},
+			kept:     []string{"1", "2", "5", "6"},
+			dropped:  2,
+		},
+		"first and last, not full": {
+			factory:  FirstLastErrors{First: 2, Last: 2},
+			appended: []string{"1", "2", "3"},
+			kept:     []string{"1", "2", "3"},
+		},
+		"first only": {
+			factory:  FirstLastErrors{First: 1},
+			appended: []string{"1", "2", "3"},
+			kept:     []string{"1"},
+			dropped:  2,
+		},
+		"ring": {
+			factory:  RingErrors{Size: 3},
+			appended: []string{"1", "2", "3", "4", "5"},
+			kept:     []string{"3", "4", "5"},
+			dropped:  2,
+		},
+		"count": {
+			factory:  CountErrors{},
+			appended: []string{"a", "b", "a", "a", "c"},
+			kept:     []string{"a", "b", "c"},
+			dropped:  2,
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			list := c.factory.New()
+			for _, message := range c.appended {
+				list.Append(errors.New(message))
+			}
+			list.SetReason(ErrExhausted)
+			kept := list.Errors()
+			if len(kept) != len(c.kept) {
+				t.Fatalf(`expected %d errors kept, but got %d: %v`, len(c.kept), len(kept), kept)
+			}
+			for i := range c.kept {
+				if kept[i].Error() != c.kept[i] {
+					t.Errorf(`error %d: expected "%s" but got "%s"`, i, c.kept[i], kept[i].Error())
+				}
+			}
+			if actual := Dropped(list); actual != c.dropped {
+				t.Errorf(`expected %d dropped, but got %d`, c.dropped, actual)
+			}
+			if list.Last().Error() != c.appended[len(c.appended)-1] {
+				t.Errorf(`expected the last error to be "%s" even if it was not kept, but got "%s"`, c.appended[len(c.appended)-1], list.Last())
+			}
+			if !errors.Is(list, ErrExhausted) {
+				t.Error(`expected errors.Is to find the reason`)
+			}
+		})
+	}
+}
+
+func TestCountErrors_Counts(t *testing.T) {
+	list := CountErrors{}.New()
+	for i := 0; i < 3; i++ {
+		list.Append(errors.New("refused"))
+	}
+	list.Append(errors.New("timeout"))
+	counts := list.(ErrorCounter).Counts()
+	if len(counts) != 2 || counts[0].Count != 3 || counts[1].Count != 1 {
+		t.Errorf(`expected refused x3 and timeout x1, but got %v`, counts)
+	}
+	expected := "retries exceeded; encountered errors: refused (x3), timeout (x1)"
+	if list.Error() != expected {
+		t.Errorf(`expected "%s" but got "%s"`, expected, list.Error())
+	}
+}
+
+func TestFirstLastErrors_Error(t *testing.T) {
+	list := FirstLastErrors{First: 1, Last: 1}.New()
+	for i := 1; i <= 4; i++ {
+		list.Append(fmt.Errorf("%d", i))
+	}
+	expected := "retries exceeded; encountered errors: 1, (2 dropped), 4"
+	if list.Error() != expected {
+		t.Errorf(`expected "%s" but got "%s"`, expected, list.Error())
+	}
+}
+
+// TestBoundedErrorLists_Service ensures a Service records errors in its configured list
+func TestBoundedErrorLists_Service(t *testing.T) {
+	errList := How(MaxAttempts{
+		Times:     100,
+		Clock:     retrytest.NewAutoClock(time.Now()),
+		ErrorList: RingErrors{Size: 5},
+	}.New()).This(func(controller ServiceController) error {
+		return errors.New("boom")
+	})
+	if len(errList.Errors()) != 5 {
+		t.Errorf(`expected 5 errors kept, but got %d`, len(errList.Errors()))
+	}
+	if Dropped(errList) != 95 {
+		t.Errorf(`expected 95 errors dropped, but got %d`, Dropped(errList))
+	}
+}
+
+func TestDropped_Unbounded(t *testing.T) {
+	list := newErrorList()
+	list.Append(errors.New("boom"))
+	if Dropped(list) != 0 || Dropped(nil) != 0 {
+		t.Error(`expected nothing dropped from the default list`)
+	}
+}
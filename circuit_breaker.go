@@ -0,0 +1,300 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is recorded, and is the Reason, when a CircuitBreaker refuses to let an attempt happen
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets every attempt through. This is the starting state.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails every attempt fast, without making it, until the CoolDown has passed
+	CircuitOpen
+
+	// CircuitHalfOpen lets a limited number of probe attempts through to see if the dependency has recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops every caller from retrying a dependency that keeps failing. Share one CircuitBreaker between
+// all calls to the same dependency and Wrap each call's Service with it. Configure it before first use; it is then
+// safe for concurrent use.
+//
+// While closed, attempts are counted and the breaker opens (trips) when either of the trip conditions is met. While
+// open, attempts fail at once with ErrCircuitOpen. Once the CoolDown has passed, the breaker becomes half-open and
+// lets HalfOpenAttempts probes through: if they all succeed the breaker closes, if any fail it opens again.
+type CircuitBreaker struct {
+	// ConsecutiveFailures trips the breaker after this many failed attempts in a row (leave as 0 to ignore)
+	ConsecutiveFailures uint
+
+	// FailureRatio trips the breaker when at least this proportion of attempts failed, e.g. 0.5 for half of them
+	// (leave as 0 to ignore)
+	FailureRatio float64
+
+	// MinimumAttempts is the number of attempts that must be counted before FailureRatio can trip the breaker
+	MinimumAttempts uint
+
+	// Window restarts the counts used by FailureRatio this often (leave as 0 to only restart them when the breaker
+	// closes)
+	Window time.Duration
+
+	// CoolDown is how long the breaker stays open before letting probes through
+	CoolDown time.Duration
+
+	// HalfOpenAttempts is how many probe attempts are let through while half-open. They all need to succeed to close
+	// the breaker. Leave as 0 to use 1.
+	HalfOpenAttempts uint
+
+	// IsFailure decides which errors count against the dependency. Leave nil to count every error except those caused
+	// by the caller's own context ending, which match ErrContextDone.
+	IsFailure func(err error) bool
+
+	// OnStateChange, if set, is called after each change of state. It is called without any locks held.
+	OnStateChange func(from, to CircuitState)
+
+	// Clock tells the time for the CoolDown and Window. Leave nil to use the system clock.
+	Clock Clock
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  uint64
+	openedAt    time.Time
+	windowStart time.Time
+	attempts    uint
+	failures    uint
+	consecutive uint
+	probes      uint
+	probesOK    uint
+}
+
+// Wrap returns a Service that follows svc, but only makes attempts that the breaker allows
+func (b *CircuitBreaker) Wrap(svc Service) Service {
+	return &circuitService{
+		wrapper: wrapper{Service: svc},
+		breaker: b,
+	}
+}
+
+// State returns the current state of the breaker. An open breaker is half-open once its CoolDown has passed, even
+// if no attempt has been made since.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	from := b.state
+	b.coolDown(b.clock().Now())
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return to
+}
+
+// coolDown moves an open breaker to half-open once the CoolDown has passed. Must be called with the lock held.
+func (b *CircuitBreaker) coolDown(now time.Time) {
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.CoolDown {
+		b.setState(CircuitHalfOpen, now)
+	}
+}
+
+// allow lets an attempt through, if the breaker is in a state to. Returns the generation of the state that allowed
+// it, so the outcome of attempts allowed by an earlier state can be ignored.
+func (b *CircuitBreaker) allow() (generation uint64, err error) {
+	b.mu.Lock()
+	from := b.state
+	b.coolDown(b.clock().Now())
+	switch b.state {
+	case CircuitOpen:
+		err = ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probes >= b.halfOpenAttempts() {
+			err = ErrCircuitOpen
+		} else {
+			b.probes++
+		}
+	}
+	generation = b.generation
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return generation, err
+}
+
+// release gives back a probe that was allowed, but never attempted
+func (b *CircuitBreaker) release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation == b.generation && b.state == CircuitHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// done records the outcome of an attempt that was allowed
+func (b *CircuitBreaker) done(generation uint64, err error) {
+	b.mu.Lock()
+	from := b.state
+	if generation != b.generation {
+		// the state changed while this attempt was running, its outcome no longer matters
+		b.mu.Unlock()
+		return
+	}
+	failed := b.isFailure(err)
+	now := b.clock().Now()
+	switch b.state {
+	case CircuitClosed:
+		if b.Window > 0 && now.Sub(b.windowStart) >= b.Window {
+			b.windowStart = now
+			b.attempts = 0
+			b.failures = 0
+		}
+		b.attempts++
+		if failed {
+			b.failures++
+			b.consecutive++
+		} else {
+			b.consecutive = 0
+		}
+		if b.shouldTrip() {
+			b.setState(CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			b.setState(CircuitOpen, now)
+		} else {
+			b.probesOK++
+			if b.probesOK >= b.halfOpenAttempts() {
+				b.setState(CircuitClosed, now)
+			}
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// isFailure is true if err counts against the dependency
+func (b *CircuitBreaker) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if b.IsFailure != nil {
+		return b.IsFailure(err)
+	}
+	return !errors.Is(err, ErrContextDone)
+}
+
+// shouldTrip is true if either of the trip conditions is met
+func (b *CircuitBreaker) shouldTrip() bool {
+	if b.ConsecutiveFailures > 0 && b.consecutive >= b.ConsecutiveFailures {
+		return true
+	}
+	return b.FailureRatio > 0 && b.attempts > 0 && b.attempts >= b.MinimumAttempts &&
+		float64(b.failures)/float64(b.attempts) >= b.FailureRatio
+}
+
+// setState moves to the state and starts its counts over. Must be called with the lock held.
+func (b *CircuitBreaker) setState(state CircuitState, now time.Time) {
+	b.state = state
+	b.generation++
+	b.openedAt = now
+	b.windowStart = now
+	b.attempts = 0
+	b.failures = 0
+	b.consecutive = 0
+	b.probes = 0
+	b.probesOK = 0
+}
+
+// notify calls OnStateChange if the state changed. Must be called without the lock held.
+func (b *CircuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+func (b *CircuitBreaker) halfOpenAttempts() uint {
+	if b.HalfOpenAttempts == 0 {
+		return 1
+	}
+	return b.HalfOpenAttempts
+}
+
+func (b *CircuitBreaker) clock() Clock {
+	return clockOrDefault(b.Clock)
+}
+
+// circuitService asks the breaker before each attempt and tells it the outcome. Everything else is up to the wrapped
+// Service.
+type circuitService struct {
+	wrapper
+	breaker    *CircuitBreaker
+	generation uint64
+}
+
+// ShouldTry is false if the wrapped service is done, or if the breaker is open; there is no point waiting to fail
+func (c *circuitService) ShouldTry() bool {
+	return c.Service.ShouldTry() && c.breaker.State() != CircuitOpen
+}
+
+// Reason explains why ShouldTry is false
+func (c *circuitService) Reason() error {
+	if reason := c.wrapper.Reason(); reason != nil {
+		return reason
+	}
+	if c.breaker.State() == CircuitOpen {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// AllowAttempt asks the breaker, and then the wrapped service, for permission to attempt
+func (c *circuitService) AllowAttempt() error {
+	generation, err := c.breaker.allow()
+	if err != nil {
+		return err
+	}
+	c.generation = generation
+	if err := c.wrapper.AllowAttempt(); err != nil {
+		// the attempt will not happen after all, so it neither failed nor succeeded
+		c.breaker.release(generation)
+		return err
+	}
+	return nil
+}
+
+// AttemptDone tells the breaker, and then the wrapped service, how the attempt went
+func (c *circuitService) AttemptDone(err error) {
+	c.breaker.done(c.generation, err)
+	c.wrapper.AttemptDone(err)
+}
@@ -0,0 +1,223 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func alwaysFail(controller ServiceController) error {
+	return errors.New("boom")
+}
+
+func alwaysSucceed(controller ServiceController) error {
+	return nil
+}
+
+func TestCircuitBreaker_ConsecutiveFailures(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	var changes []string
+	breaker := &CircuitBreaker{
+		ConsecutiveFailures: 3,
+		CoolDown:            1 * time.Minute,
+		Clock:               clock,
+		OnStateChange: func(from, to CircuitState) {
+			changes = append(changes, from.String()+" -> "+to.String())
+		},
+	}
+	policy := MaxAttempts{Times: 5, Clock: retrytest.NewAutoClock(time.Now())}
+
+	attempts := 0
+	errList := How(breaker.Wrap(policy.New())).This(func(controller ServiceController) error {
+		attempts++
+		return errors.New("boom")
+	})
+	if attempts != 3 {
+		t.Errorf(`expected the breaker to stop the retries after 3 attempts, got: %d`, attempts)
+	}
+	if errList.Reason() != ErrCircuitOpen {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrCircuitOpen, errList.Reason())
+	}
+
+	// while open, fail fast
+	errList = How(breaker.Wrap(policy.New())).This(func(controller ServiceController) error {
+		t.Error("expected no attempt while the breaker is open")
+		return nil
+	})
+	if errList == nil || errList.Last() != ErrCircuitOpen || errList.Reason() != ErrCircuitOpen {
+		t.Errorf(`expected to fail fast with "%v", got: "%v"`, ErrCircuitOpen, errList)
+	}
+
+	// after the cool down, a successful probe closes the breaker
+	clock.Advance(1 * time.Minute)
+	if errList = How(breaker.Wrap(policy.New())).This(alwaysSucceed); errList != nil {
+		t.Errorf(`expected the probe to succeed, got: "%v"`, errList)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf(`expected the breaker to be closed, but was: %v`, breaker.State())
+	}
+
+	expected := []string{"closed -> open", "open -> half-open", "half-open -> closed"}
+	if len(changes) != len(expected) {
+		t.Fatalf(`expected state changes %v, got: %v`, expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf(`expected state change: "%s" but got: "%s"`, expected[i], changes[i])
+		}
+	}
+}
+
+func TestCircuitBreaker_FailedProbe(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	breaker := &CircuitBreaker{ConsecutiveFailures: 1, CoolDown: 1 * time.Minute, Clock: clock}
+	_ = How(breaker.Wrap(MaxAttempts{Times: 1}.New())).This(alwaysFail)
+	clock.Advance(1 * time.Minute)
+
+	attempts := 0
+	_ = How(breaker.Wrap(MaxAttempts{Times: 3}.New())).This(func(controller ServiceController) error {
+		attempts++
+		return errors.New("still down")
+	})
+	if attempts != 1 {
+		t.Errorf(`expected a single probe attempt, got: %d`, attempts)
+	}
+	if breaker.State() != CircuitOpen {
+		t.Errorf(`expected the failed probe to open the breaker, but was: %v`, breaker.State())
+	}
+}
+
+func TestCircuitBreaker_FailureRatio(t *testing.T) {
+	breaker := &CircuitBreaker{FailureRatio: 0.5, MinimumAttempts: 4, CoolDown: 1 * time.Minute}
+	outcomes := []func(ServiceController) error{alwaysSucceed, alwaysFail, alwaysSucceed}
+	for _, outcome := range outcomes {
+		_ = How(breaker.Wrap(MaxAttempts{Times: 1}.New())).This(outcome)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Fatalf(`expected the breaker to wait for the minimum number of attempts, but was: %v`, breaker.State())
+	}
+	_ = How(breaker.Wrap(MaxAttempts{Times: 1}.New())).This(alwaysFail)
+	if breaker.State() != CircuitOpen {
+		t.Errorf(`expected half of the attempts failing to open the breaker, but was: %v`, breaker.State())
+	}
+}
+
+func TestCircuitBreaker_IsFailure(t *testing.T) {
+	breaker := &CircuitBreaker{
+		ConsecutiveFailures: 1,
+		IsFailure: func(err error) bool {
+			return !IsPermanent(err)
+		},
+	}
+	_ = How(breaker.Wrap(MaxAttempts{Times: 1}.New())).This(func(controller ServiceController) error {
+		return Permanent(errors.New("bad input"))
+	})
+	if breaker.State() != CircuitClosed {
+		t.Errorf(`expected errors that are not failures to be ignored, but was: %v`, breaker.State())
+	}
+}
+
+// TestCircuitBreaker_CallerContextDone ensures that a caller giving up does not count against the dependency
+func TestCircuitBreaker_CallerContextDone(t *testing.T) {
+	breaker := &CircuitBreaker{ConsecutiveFailures: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = How(breaker.Wrap(MaxAttempts{Times: 2}.New())).ThisContext(ctx, func(ctx context.Context, controller ServiceController) error {
+		cancel()
+		return ctx.Err()
+	})
+	if breaker.State() != CircuitClosed {
+		t.Errorf(`expected the caller's context to be ignored, but was: %v`, breaker.State())
+	}
+}
+
+// TestCircuitBreaker_StateAfterCoolDown ensures that the breaker reports half-open once the CoolDown has passed,
+// without waiting for an attempt
+func TestCircuitBreaker_StateAfterCoolDown(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	breaker := &CircuitBreaker{ConsecutiveFailures: 1, CoolDown: 1 * time.Minute, Clock: clock}
+	_ = How(breaker.Wrap(MaxAttempts{Times: 1}.New())).This(alwaysFail)
+	if breaker.State() != CircuitOpen {
+		t.Fatalf(`expected the breaker to be open, but was: %v`, breaker.State())
+	}
+	svc := breaker.Wrap(MaxAttempts{Times: 1}.New())
+	if svc.ShouldTry() {
+		t.Error("expected not to try while open")
+	}
+	clock.Advance(1 * time.Minute)
+	if breaker.State() != CircuitHalfOpen {
+		t.Errorf(`expected the breaker to be half-open, but was: %v`, breaker.State())
+	}
+	if !svc.ShouldTry() {
+		t.Error("expected to try once the cool down has passed")
+	}
+}
+
+func TestCircuitBreaker_HalfOpenLimitsProbes(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	breaker := &CircuitBreaker{ConsecutiveFailures: 1, CoolDown: 1 * time.Minute, Clock: clock}
+	_ = How(breaker.Wrap(MaxAttempts{Times: 1}.New())).This(alwaysFail)
+	clock.Advance(1 * time.Minute)
+
+	probing := make(chan struct{})
+	finishProbe := make(chan struct{})
+	done := make(chan Errorer)
+	go func() {
+		done <- How(breaker.Wrap(MaxAttempts{Times: 1}.New())).This(func(controller ServiceController) error {
+			close(probing)
+			<-finishProbe
+			return nil
+		})
+	}()
+	<-probing
+
+	errList := How(breaker.Wrap(MaxAttempts{Times: 1}.New())).This(func(controller ServiceController) error {
+		t.Error("expected no attempt while the probe is in flight")
+		return nil
+	})
+	if errList == nil || errList.Reason() != ErrCircuitOpen {
+		t.Errorf(`expected to fail fast with "%v", got: "%v"`, ErrCircuitOpen, errList)
+	}
+	close(finishProbe)
+	if errList = <-done; errList != nil {
+		t.Errorf(`expected the probe to succeed, got: "%v"`, errList)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf(`expected the breaker to be closed, but was: %v`, breaker.State())
+	}
+}
+
+func TestCircuitBreaker_Concurrent(t *testing.T) {
+	breaker := &CircuitBreaker{FailureRatio: 0.9, MinimumAttempts: 10, CoolDown: 1 * time.Millisecond}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = How(breaker.Wrap(MaxAttempts{Times: 3}.New())).This(func(controller ServiceController) error {
+				if i%2 == 0 {
+					return errors.New("boom")
+				}
+				return nil
+			})
+			_ = breaker.State()
+		}(i)
+	}
+	wg.Wait()
+}
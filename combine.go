@@ -0,0 +1,438 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// These combinators build a Service out of other Services, e.g. "at most 10 attempts AND follow ExpBase2". Each child
+// is a Service made by New or NewWithContext, so create a new combination for each thing to retry. At most one child
+// should be wrapped by a CircuitBreaker or RetryBudget; prefer wrapping the combination with those.
+
+// WaitSelector picks which child decides the wait. It is given the planned wait of each child that still wants to try
+// and returns the index of the chosen one. The chosen child then yields its own wait.
+type WaitSelector func(waits []time.Duration) int
+
+// MaxWait selects the child with the longest wait
+func MaxWait(waits []time.Duration) int {
+	selected := 0
+	for i, wait := range waits {
+		if wait > waits[selected] {
+			selected = i
+		}
+	}
+	return selected
+}
+
+// MinWait selects the child with the shortest wait
+func MinWait(waits []time.Duration) int {
+	selected := 0
+	for i, wait := range waits {
+		if wait < waits[selected] {
+			selected = i
+		}
+	}
+	return selected
+}
+
+// All creates a Service that keeps trying only while every child's ShouldTry is true, and waits for the longest of
+// their waits
+func All(children ...Service) Service {
+	return AllWith(MaxWait, children...)
+}
+
+// AllWith works like All, but selector picks the wait
+func AllWith(selector WaitSelector, children ...Service) Service {
+	return &combinedService{
+		children: children,
+		selector: selector,
+	}
+}
+
+// Any creates a Service that keeps trying while any child's ShouldTry is true, and waits for the longest of the waits
+// of the children that are still trying
+func Any(children ...Service) Service {
+	return AnyWith(MaxWait, children...)
+}
+
+// AnyWith works like Any, but selector picks the wait
+func AnyWith(selector WaitSelector, children ...Service) Service {
+	return &combinedService{
+		children: children,
+		selector: selector,
+		any:      true,
+	}
+}
+
+// combinedService asks every child whether to try. NotifyRetry, Abort and RetryAfter go to every child.
+type combinedService struct {
+	attemptTracker
+	children []Service
+	selector WaitSelector
+	// any is true to continue while any child wants to, false to continue only while all of them do
+	any bool
+}
+
+// ShouldTry is true while all, or any, of the children should try. Always false without children.
+func (c *combinedService) ShouldTry() bool {
+	if len(c.children) == 0 {
+		return false
+	}
+	for _, child := range c.children {
+		if child.ShouldTry() == c.any {
+			return c.any
+		}
+	}
+	return !c.any
+}
+
+// Reason explains why ShouldTry is false. For All, it is the reason of the first child to stop. For Any, it is the
+// reason of the first child.
+func (c *combinedService) Reason() error {
+	if c.ShouldTry() {
+		return nil
+	}
+	for _, child := range c.children {
+		if c.any || !child.ShouldTry() {
+			if reason := reasonOf(child); reason != nil {
+				return reason
+			}
+			return ErrExhausted
+		}
+	}
+	return ErrExhausted
+}
+
+// selected returns the child that decides the wait
+func (c *combinedService) selected() Service {
+	candidates := make([]Service, 0, len(c.children))
+	for _, child := range c.children {
+		if child.ShouldTry() {
+			candidates = append(candidates, child)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = c.children
+	}
+	waits := make([]time.Duration, len(candidates))
+	for i, candidate := range candidates {
+		waits[i] = nextWaitOf(candidate)
+	}
+	return candidates[c.selector(waits)]
+}
+
+// Yield waits as long as the selected child does
+func (c *combinedService) Yield() {
+	c.YieldContext(context.Background())
+}
+
+// YieldContext waits as long as the selected child does, or until ctx is done. For All, the wait also ends as soon as
+// any other child stops, e.g. because its context is done. Every child is then told the wait is over.
+func (c *combinedService) YieldContext(ctx context.Context) {
+	selected := c.selected()
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if !c.any {
+		for _, child := range c.children {
+			if done := doneOf(child); done != nil && child != selected {
+				go func() {
+					select {
+					case <-done:
+						// this child will stop, so there is no point waiting
+						cancel()
+					case <-waitCtx.Done():
+					}
+				}()
+			}
+		}
+	}
+	yieldContext(selected, waitCtx)
+	c.markYielded()
+}
+
+// markYielded tells every child that the wait is over
+func (c *combinedService) markYielded() {
+	for _, child := range c.children {
+		markYielded(child)
+	}
+}
+
+// NextWait returns the selected child's wait
+func (c *combinedService) NextWait() time.Duration {
+	return nextWaitOf(c.selected())
+}
+
+// AttemptTimeout returns the shortest limit of the children, or 0 for no limit
+func (c *combinedService) AttemptTimeout() time.Duration {
+	var shortest time.Duration
+	for _, child := range c.children {
+		if timeout := attemptTimeoutOf(child); timeout > 0 && (shortest == 0 || timeout < shortest) {
+			shortest = timeout
+		}
+	}
+	return shortest
+}
+
+// Clock returns the first child's clock
+func (c *combinedService) Clock() Clock {
+	if len(c.children) == 0 {
+		return realClock{}
+	}
+	return clockOf(c.children[0])
+}
+
+// AllowAttempt asks each child that is an AttemptGate, stopping at the first to refuse
+func (c *combinedService) AllowAttempt() error {
+	c.start(c.Clock())
+	for _, child := range c.children {
+		if gate, ok := child.(AttemptGate); ok {
+			if err := gate.AllowAttempt(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AttemptDone tells each child that is an AttemptGate how the attempt went
+func (c *combinedService) AttemptDone(err error) {
+	c.previousErr = err
+	for _, child := range c.children {
+		if gate, ok := child.(AttemptGate); ok {
+			gate.AttemptDone(err)
+		}
+	}
+}
+
+func (c *combinedService) Controller() ServiceController {
+	return c
+}
+
+// Abort aborts every child
+func (c *combinedService) Abort() {
+	for _, child := range c.children {
+		child.Controller().Abort()
+	}
+}
+
+// RetryAfter passes the request to every child
+func (c *combinedService) RetryAfter(d time.Duration) {
+	for _, child := range c.children {
+		retryAfter(child.Controller(), d)
+	}
+}
+
+// NotifyRetry tells every child about the attempt
+func (c *combinedService) NotifyRetry() {
+	c.notify(c.Clock())
+	for _, child := range c.children {
+		child.NotifyRetry()
+	}
+}
+
+// Elapsed returns how long ago the first attempt started
+func (c *combinedService) Elapsed() time.Duration {
+	return c.elapsed(c.Clock())
+}
+
+// Remaining returns the fewest attempts any child has left for All, or the most for Any
+func (c *combinedService) Remaining() uint {
+	if len(c.children) == 0 {
+		return 0
+	}
+	remaining := remainingOf(c.children[0])
+	for _, child := range c.children[1:] {
+		if r := remainingOf(child); (c.any && r > remaining) || (!c.any && r < remaining) {
+			remaining = r
+		}
+	}
+	return remaining
+}
+
+// NewErrorList creates the first child's error list
+func (c *combinedService) NewErrorList() ErrorAppender {
+	if len(c.children) == 0 {
+		return newErrorList()
+	}
+	return c.children[0].NewErrorList()
+}
+
+// Sequence creates a Service that follows first until it runs out of attempts, then follows then. If first stops for
+// another reason, such as Abort or its context ending, so does the Sequence. Unlike All and Any, NotifyRetry only goes
+// to the child being followed, so then starts counting its attempts when it takes over. A RetryAfter requested on
+// first's last attempt is not carried over to then.
+func Sequence(first, then Service) Service {
+	return &sequenceService{
+		children: [2]Service{first, then},
+	}
+}
+
+// sequenceService follows one child, then the other
+type sequenceService struct {
+	attemptTracker
+	children [2]Service
+	// current is the index of the child being followed
+	current int
+}
+
+// active returns the child being followed
+func (s *sequenceService) active() Service {
+	return s.children[s.current]
+}
+
+func (s *sequenceService) ShouldTry() bool {
+	return s.active().ShouldTry()
+}
+
+func (s *sequenceService) Reason() error {
+	return reasonOf(s.active())
+}
+
+func (s *sequenceService) Yield() {
+	s.active().Yield()
+}
+
+func (s *sequenceService) YieldContext(ctx context.Context) {
+	yieldContext(s.active(), ctx)
+}
+
+func (s *sequenceService) done() <-chan struct{} {
+	return doneOf(s.active())
+}
+
+func (s *sequenceService) markYielded() {
+	markYielded(s.active())
+}
+
+func (s *sequenceService) NextWait() time.Duration {
+	return nextWaitOf(s.active())
+}
+
+func (s *sequenceService) AttemptTimeout() time.Duration {
+	return attemptTimeoutOf(s.active())
+}
+
+func (s *sequenceService) Clock() Clock {
+	return clockOf(s.active())
+}
+
+func (s *sequenceService) AllowAttempt() error {
+	s.start(s.Clock())
+	if gate, ok := s.active().(AttemptGate); ok {
+		return gate.AllowAttempt()
+	}
+	return nil
+}
+
+func (s *sequenceService) AttemptDone(err error) {
+	s.previousErr = err
+	if gate, ok := s.active().(AttemptGate); ok {
+		gate.AttemptDone(err)
+	}
+}
+
+func (s *sequenceService) Controller() ServiceController {
+	return s
+}
+
+// Abort aborts both children
+func (s *sequenceService) Abort() {
+	for _, child := range s.children {
+		child.Controller().Abort()
+	}
+}
+
+// RetryAfter passes the request to the child being followed
+func (s *sequenceService) RetryAfter(d time.Duration) {
+	retryAfter(s.active().Controller(), d)
+}
+
+// NotifyRetry tells the child being followed about the attempt, and moves on to then once first has run out
+func (s *sequenceService) NotifyRetry() {
+	s.notify(s.Clock())
+	s.active().NotifyRetry()
+	if s.current == 0 && !s.children[0].ShouldTry() {
+		if reason := reasonOf(s.children[0]); reason == nil || errors.Is(reason, ErrExhausted) {
+			s.current = 1
+		}
+	}
+}
+
+// Elapsed returns how long ago the first attempt started
+func (s *sequenceService) Elapsed() time.Duration {
+	return s.elapsed(s.Clock())
+}
+
+// Remaining returns how many attempts the child being followed has left, plus those of then while following first
+func (s *sequenceService) Remaining() uint {
+	remaining := remainingOf(s.active())
+	if s.current == 1 {
+		return remaining
+	}
+	then := remainingOf(s.children[1])
+	if remaining == Unlimited || then == Unlimited {
+		return Unlimited
+	}
+	// then has not started, so its attempt in progress is still to come
+	return remaining + then + 1
+}
+
+func (s *sequenceService) NewErrorList() ErrorAppender {
+	return s.children[0].NewErrorList()
+}
+
+// attemptTracker answers AttemptInfo for Services that combine others
+type attemptTracker struct {
+	triesSoFar  uint
+	startedAt   time.Time
+	started     bool
+	previousErr error
+}
+
+// start notes when the first attempt was made
+func (t *attemptTracker) start(clock Clock) {
+	if !t.started {
+		t.startedAt = clock.Now()
+		t.started = true
+	}
+}
+
+// notify counts an attempt
+func (t *attemptTracker) notify(clock Clock) {
+	// in case the attempt was made without asking AllowAttempt first
+	t.start(clock)
+	t.triesSoFar++
+}
+
+// elapsed returns how long ago the first attempt started
+func (t *attemptTracker) elapsed(clock Clock) time.Duration {
+	if !t.started {
+		return 0
+	}
+	return clock.Now().Sub(t.startedAt)
+}
+
+// Attempt returns the number of the attempt in progress, starting at 1
+func (t *attemptTracker) Attempt() uint {
+	return t.triesSoFar + 1
+}
+
+// PreviousError returns the error of the previous attempt, or nil during the first
+func (t *attemptTracker) PreviousError() error {
+	return t.previousErr
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"testing"
+	"time"
+)
+
+func TestCombinators(t *testing.T) {
+	cases := map[string]struct {
+		svc      func(clock Clock) Service
+		attempts int
+		waits    []time.Duration
+	}{
+		"all waits for the longest": {
+			svc: func(clock Clock) Service {
+				return All(
+					MaxAttempts{Times: 3, WaitFor: 3 * time.Second, Clock: clock}.New(),
+					ExpBase2{Times: 10, Scaling: time.Second, Clock: clock}.New(),
+				)
+			},
+			attempts: 3,
+			waits:    []time.Duration{3 * time.Second, 3 * time.Second},
+		},
+		"all with the shortest wait": {
+			svc: func(clock Clock) Service {
+				return AllWith(MinWait,
+					MaxAttempts{Times: 5, WaitFor: 3 * time.Second, Clock: clock}.New(),
+					ExpBase2{Times: 5, Scaling: time.Second, Clock: clock}.New(),
+				)
+			},
+			attempts: 5,
+			waits:    []time.Duration{1 * time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		"any ignores children that stopped": {
+			svc: func(clock Clock) Service {
+				return Any(
+					MaxAttempts{Times: 2, WaitFor: 5 * time.Second, Clock: clock}.New(),
+					ExpBase2{Times: 4, Scaling: time.Second, Clock: clock}.New(),
+				)
+			},
+			attempts: 4,
+			waits:    []time.Duration{5 * time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		"sequence": {
+			svc: func(clock Clock) Service {
+				return Sequence(
+					MaxAttempts{Times: 2, WaitFor: time.Second, Clock: clock}.New(),
+					ExpBase2{Times: 3, Scaling: 10 * time.Second, Clock: clock}.New(),
+				)
+			},
+			attempts: 5,
+			waits:    []time.Duration{1 * time.Second, 10 * time.Second, 10 * time.Second, 20 * time.Second},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			clock := retrytest.NewAutoClock(time.Now())
+			attempts := 0
+			errList := How(c.svc(clock)).This(func(controller ServiceController) error {
+				attempts++
+				return errors.New("boom")
+			})
+			if attempts != c.attempts {
+				t.Errorf(`expected %d attempts, but got %d`, c.attempts, attempts)
+			}
+			if !errors.Is(errList.Reason(), ErrExhausted) {
+				t.Errorf(`expected reason: %v but got %v`, ErrExhausted, errList.Reason())
+			}
+			actual := clock.Waits()
+			if len(actual) != len(c.waits) {
+				t.Fatalf(`expected waits %v, but got %v`, c.waits, actual)
+			}
+			for i := range c.waits {
+				if c.waits[i] != actual[i] {
+					t.Errorf(`wait %d: expected duration: %v but got %v`, i+1, c.waits[i], actual[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAll_StopsWaitingWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := All(
+		MaxAttempts{Times: 5, WaitFor: 2 * time.Second}.New(),
+		ExpBase2{Times: 5, Scaling: time.Millisecond}.NewWithContext(ctx),
+	)
+	attempts := 0
+	start := time.Now()
+	errList := How(svc).This(func(controller ServiceController) error {
+		attempts++
+		time.AfterFunc(50*time.Millisecond, cancel)
+		return errors.New("boom")
+	})
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf(`expected the wait to end when the context was cancelled, but took %v`, elapsed)
+	}
+	if attempts != 1 {
+		t.Errorf(`expected 1 attempt, but got %d`, attempts)
+	}
+	if !errors.Is(errList.Reason(), context.Canceled) {
+		t.Errorf(`expected reason: %v but got %v`, context.Canceled, errList.Reason())
+	}
+}
+
+func TestAll_TellsEveryChildItYielded(t *testing.T) {
+	clock := retrytest.NewAutoClock(time.Now())
+	// the second child must not count its own 1s wait again after waiting 10s with the first
+	svc := All(
+		MaxAttempts{Times: 5, WaitFor: 10 * time.Second, Clock: clock}.New(),
+		MaxAttempts{Times: 5, WaitFor: time.Second, MaxElapsedTime: 20*time.Second + 500*time.Millisecond, Clock: clock}.New(),
+	)
+	attempts := 0
+	How(svc).This(func(controller ServiceController) error {
+		attempts++
+		return errors.New("boom")
+	})
+	if attempts != 3 {
+		t.Errorf(`expected 3 attempts, but got %d`, attempts)
+	}
+}
+
+func TestCombinators_Abort(t *testing.T) {
+	cases := map[string]func(children ...Service) Service{
+		"all": All,
+		"any": Any,
+		"sequence": func(children ...Service) Service {
+			return Sequence(children[0], children[1])
+		},
+	}
+
+	for caseName, combine := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			first := MaxAttempts{Times: 5}.New()
+			then := MaxAttempts{Times: 5}.New()
+			attempts := 0
+			errList := How(combine(first, then)).This(func(controller ServiceController) error {
+				attempts++
+				controller.Abort()
+				return errors.New("boom")
+			})
+			if attempts != 1 {
+				t.Errorf(`expected 1 attempt, but got %d`, attempts)
+			}
+			if !errors.Is(errList.Reason(), ErrAborted) {
+				t.Errorf(`expected reason: %v but got %v`, ErrAborted, errList.Reason())
+			}
+			if first.ShouldTry() || then.ShouldTry() {
+				t.Error(`expected every child to be aborted`)
+			}
+		})
+	}
+}
+
+func TestAll_NoChildren(t *testing.T) {
+	if All().ShouldTry() {
+		t.Error(`expected not to try without children`)
+	}
+}
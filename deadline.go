@@ -0,0 +1,55 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDeadline is the Reason retrying stopped when the next wait would end past the context's deadline, so the wait
+// was skipped rather than sleeping only to give up afterwards
+var ErrDeadline = errors.New("next wait would pass the context deadline")
+
+// DeadlineMode is what a Service made by NewWithContext does when its next wait would end past the context's
+// deadline. The deadline is compared with the Service's Clock.
+type DeadlineMode int
+
+const (
+	// DeadlineGiveUp stops retrying at once with ErrDeadline. This is the default.
+	DeadlineGiveUp DeadlineMode = iota
+
+	// DeadlineLastAttempt shortens the wait so that one last attempt fits before the deadline. The wait leaves the
+	// AttemptTimeout for the attempt, or is skipped entirely without an AttemptTimeout. If the wait after that would
+	// still pass the deadline, retrying stops with ErrDeadline.
+	DeadlineLastAttempt
+)
+
+// fitDeadline shortens wait, if needed, so that an attempt taking attemptTimeout still fits before deadline. Returns
+// true if the wait was shortened.
+func fitDeadline(wait, attemptTimeout time.Duration, now, deadline time.Time) (time.Duration, bool) {
+	if !now.Add(wait).After(deadline) {
+		return wait, false
+	}
+	if attemptTimeout <= 0 {
+		// no idea how long the attempt takes, so give it as long as possible
+		return 0, true
+	}
+	fitted := deadline.Sub(now) - attemptTimeout
+	if fitted < 0 {
+		fitted = 0
+	}
+	return fitted, true
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"testing"
+	"time"
+)
+
+func TestDeadlineMode(t *testing.T) {
+	cases := map[string]struct {
+		cfg      ExpBase2
+		attempts int
+		waits    []time.Duration
+	}{
+		"give up": {
+			cfg: ExpBase2{
+				Times:   10,
+				Scaling: 10 * time.Second,
+			},
+			attempts: 3,
+			waits:    []time.Duration{10 * time.Second, 20 * time.Second},
+		},
+		"last attempt": {
+			cfg: ExpBase2{
+				Times:    10,
+				Scaling:  10 * time.Second,
+				Deadline: DeadlineLastAttempt,
+			},
+			attempts: 4,
+			waits:    []time.Duration{10 * time.Second, 20 * time.Second, 0},
+		},
+		"last attempt leaves the attempt timeout": {
+			cfg: ExpBase2{
+				Times:          10,
+				Scaling:        10 * time.Second,
+				Deadline:       DeadlineLastAttempt,
+				AttemptTimeout: 2 * time.Second,
+			},
+			attempts: 4,
+			waits:    []time.Duration{10 * time.Second, 20 * time.Second, 3 * time.Second},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			start := time.Now()
+			clock := retrytest.NewAutoClock(start)
+			ctx, cancel := context.WithDeadline(context.Background(), start.Add(35*time.Second))
+			defer cancel()
+			cfg := c.cfg
+			cfg.Clock = clock
+			attempts := 0
+			errList := How(cfg.NewWithContext(ctx)).This(func(controller ServiceController) error {
+				attempts++
+				return errors.New("boom")
+			})
+			if attempts != c.attempts {
+				t.Errorf(`expected %d attempts, but got %d`, c.attempts, attempts)
+			}
+			if !errors.Is(errList.Reason(), ErrDeadline) {
+				t.Errorf(`expected reason: %v but got %v`, ErrDeadline, errList.Reason())
+			}
+			actual := clock.Waits()
+			if len(actual) != len(c.waits) {
+				t.Fatalf(`expected waits %v, but got %v`, c.waits, actual)
+			}
+			for i := range c.waits {
+				if c.waits[i] != actual[i] {
+					t.Errorf(`wait %d: expected duration: %v but got %v`, i+1, c.waits[i], actual[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDeadlineMode_NoDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := ExpBase2{Times: 3, Scaling: time.Hour}.NewWithContext(ctx)
+	svc.NotifyRetry()
+	if !svc.ShouldTry() {
+		t.Errorf(`expected to try without a deadline, but stopped with %v`, reasonOf(svc))
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import "context"
+
+// Do retries fn according to svc and returns the value from the attempt that succeeded. If no attempt succeeded, the
+// zero value of T is returned along with the errors.
+func Do[T any](svc Service, fn func(controller ServiceController) (T, error), observers ...Observer) (T, Errorer) {
+	return DoContext(context.Background(), svc, func(_ context.Context, controller ServiceController) (T, error) {
+		return fn(controller)
+	}, observers...)
+}
+
+// DoContext works like Do, but passes ctx into each attempt, the same as Retrier.ThisContext
+func DoContext[T any](ctx context.Context, svc Service, fn func(ctx context.Context, controller ServiceController) (T, error), observers ...Observer) (T, Errorer) {
+	var result T
+	errList := How(svc, observers...).ThisContext(ctx, func(ctx context.Context, controller ServiceController) error {
+		value, err := fn(ctx, controller)
+		if err == nil {
+			result = value
+		}
+		return err
+	})
+	if errList != nil {
+		var zero T
+		return zero, errList
+	}
+	return result, nil
+}
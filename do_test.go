@@ -0,0 +1,69 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDo(t *testing.T) {
+	attempts := 0
+	value, errList := Do(MaxAttempts{Times: 3}.New(), func(controller ServiceController) (string, error) {
+		attempts++
+		if attempts < 2 {
+			return "partial", errors.New("boom")
+		}
+		return "done", nil
+	})
+	if errList != nil {
+		t.Fatalf(`expected no errors, got: "%v"`, errList)
+	}
+	if value != "done" {
+		t.Errorf(`expected value: "done" but got: "%s"`, value)
+	}
+}
+
+func TestDo_Failure(t *testing.T) {
+	value, errList := Do(MaxAttempts{Times: 2}.New(), func(controller ServiceController) (*int, error) {
+		one := 1
+		return &one, errors.New("boom")
+	})
+	if value != nil {
+		t.Errorf(`expected the zero value, got: %v`, value)
+	}
+	if errList == nil || len(errList.Errors()) != 2 {
+		t.Errorf(`expected 2 errors, got: "%v"`, errList)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, 42)
+	observer := &recordingObserver{}
+	value, errList := DoContext(ctx, MaxAttempts{Times: 1}.New(), func(ctx context.Context, controller ServiceController) (int, error) {
+		return ctx.Value(key{}).(int), nil
+	}, observer)
+	if errList != nil {
+		t.Fatalf(`expected no errors, got: "%v"`, errList)
+	}
+	if value != 42 {
+		t.Errorf(`expected value: 42 but got: %d`, value)
+	}
+	if len(observer.events) != 2 {
+		t.Errorf(`expected the observer to see the attempt and success, got: %d events`, len(observer.events))
+	}
+}
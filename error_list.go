@@ -14,14 +14,20 @@
 
 package retry
 
-import "strings"
+import (
+	"strings"
+	"sync"
+)
 
 type errorList struct {
+	attemptLog
 	recordedErrors []error
+	reason         error
 }
 
 func newErrorList() *errorList {
 	return &errorList{
+		attemptLog:     attemptLog{kept: firstLast[Attempt]{first: Unlimited}},
 		recordedErrors: make([]error, 0),
 	}
 }
@@ -47,7 +53,100 @@ func (e *errorList) Last() error {
 	return e.recordedErrors[len(e.recordedErrors)-1]
 }
 
+// Reason returns why retrying stopped
+func (e *errorList) Reason() error {
+	return e.reason
+}
+
+// Unwrap returns every error, followed by the reason, so that errors.Is and errors.As can find any of them, the same
+// as errors.Join
+func (e *errorList) Unwrap() []error {
+	if e.reason == nil {
+		return e.recordedErrors
+	}
+	unwrapped := make([]error, len(e.recordedErrors), len(e.recordedErrors)+1)
+	copy(unwrapped, e.recordedErrors)
+	return append(unwrapped, e.reason)
+}
+
 // Append adds an error to the list
 func (e *errorList) Append(err error) {
 	e.recordedErrors = append(e.recordedErrors, err)
 }
+
+// SetReason records why retrying stopped
+func (e *errorList) SetReason(reason error) {
+	e.reason = reason
+}
+
+// syncErrorList makes an ErrorAppender safe to use from many goroutines at once. Once settled, Last keeps returning
+// the error that ended the call, even if more errors are appended afterwards.
+type syncErrorList struct {
+	mu      sync.Mutex
+	list    ErrorAppender
+	settled bool
+	last    error
+}
+
+func newSyncErrorList(list ErrorAppender) *syncErrorList {
+	return &syncErrorList{
+		list: list,
+	}
+}
+
+func (e *syncErrorList) Errors() []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]error(nil), e.list.Errors()...)
+}
+
+func (e *syncErrorList) Error() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.list.Error()
+}
+
+func (e *syncErrorList) Last() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.settled {
+		return e.last
+	}
+	return e.list.Last()
+}
+
+func (e *syncErrorList) Reason() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.list.Reason()
+}
+
+func (e *syncErrorList) Unwrap() []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]error(nil), e.list.Unwrap()...)
+}
+
+func (e *syncErrorList) Append(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list.Append(err)
+}
+
+func (e *syncErrorList) SetReason(reason error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list.SetReason(reason)
+}
+
+// settle records why the call ended and fixes Last to last, or to the latest error so far if last is nil
+func (e *syncErrorList) settle(last error, reason error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if last == nil {
+		last = e.list.Last()
+	}
+	e.last = last
+	e.settled = true
+	e.list.SetReason(reason)
+}
@@ -1,6 +1,11 @@
 package retry
 
-import "testing"
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
 
 func TestErrorList_Last(t *testing.T) {
 	nothing := newErrorList()
@@ -8,3 +13,20 @@ func TestErrorList_Last(t *testing.T) {
 		t.Error("expected an empty error list to return nil and not panic when asked for the last item")
 	}
 }
+
+func TestErrorList_Unwrap(t *testing.T) {
+	el := newErrorList()
+	el.Append(io.EOF)
+	el.Append(errors.New("boom"))
+	if !errors.Is(el, io.EOF) {
+		t.Error("expected errors.Is to find an earlier error in the list")
+	}
+	if errors.Is(el, io.ErrUnexpectedEOF) {
+		t.Error("expected errors.Is to not find an error that was never recorded")
+	}
+	var pathErr *os.PathError
+	el.Append(&os.PathError{Op: "open", Path: "/nowhere", Err: os.ErrNotExist})
+	if !errors.As(el, &pathErr) {
+		t.Error("expected errors.As to find the path error")
+	}
+}
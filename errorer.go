@@ -0,0 +1,63 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import "errors"
+
+// AnyIs reports whether any error recorded by err matches target, using errors.Is. Returns false if err is nil.
+func AnyIs(err Errorer, target error) bool {
+	if err == nil {
+		return false
+	}
+	for _, recorded := range err.Errors() {
+		if errors.Is(recorded, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnyAs finds the first error recorded by err that matches target, using errors.As. Returns false if err is nil.
+func AnyAs(err Errorer, target interface{}) bool {
+	if err == nil {
+		return false
+	}
+	for _, recorded := range err.Errors() {
+		if errors.As(recorded, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// LastIs reports whether the last error recorded by err matches target, using errors.Is. Returns false if err is nil.
+func LastIs(err Errorer, target error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err.Last(), target)
+}
+
+// LastAs reports whether the last error recorded by err matches target, using errors.As. Returns false if err is nil.
+func LastAs(err Errorer, target interface{}) bool {
+	if err == nil {
+		return false
+	}
+	last := err.Last()
+	if last == nil {
+		return false
+	}
+	return errors.As(last, target)
+}
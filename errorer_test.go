@@ -0,0 +1,73 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestErrorer_Helpers(t *testing.T) {
+	el := newErrorList()
+	el.Append(&os.PathError{Op: "open", Path: "/nowhere", Err: os.ErrNotExist})
+	el.Append(io.EOF)
+
+	if !AnyIs(el, os.ErrNotExist) {
+		t.Error("expected AnyIs to find the wrapped not exist error")
+	}
+	if AnyIs(el, io.ErrUnexpectedEOF) {
+		t.Error("expected AnyIs to not find an error that was never recorded")
+	}
+	var pathErr *os.PathError
+	if !AnyAs(el, &pathErr) || pathErr.Path != "/nowhere" {
+		t.Error("expected AnyAs to find the path error")
+	}
+	if !LastIs(el, io.EOF) {
+		t.Error("expected LastIs to match the last error")
+	}
+	if LastIs(el, os.ErrNotExist) {
+		t.Error("expected LastIs to only look at the last error")
+	}
+	if LastAs(el, &pathErr) {
+		t.Error("expected LastAs to only look at the last error")
+	}
+}
+
+func TestErrorer_Helpers_Nil(t *testing.T) {
+	var pathErr *os.PathError
+	if AnyIs(nil, io.EOF) || AnyAs(nil, &pathErr) || LastIs(nil, io.EOF) || LastAs(nil, &pathErr) {
+		t.Error("expected a nil Errorer to match nothing")
+	}
+	if LastAs(newErrorList(), &pathErr) {
+		t.Error("expected an empty Errorer to match nothing")
+	}
+}
+
+// TestRetry_ErrorsIs ensures that the result of This works with the standard library's errors package
+func TestRetry_ErrorsIs(t *testing.T) {
+	attempt := 0
+	var err error = How(MaxAttempts{Times: 2}.New()).This(func(controller ServiceController) error {
+		attempt++
+		if attempt == 1 {
+			return io.EOF
+		}
+		return errors.New("boom")
+	})
+	if !errors.Is(err, io.EOF) {
+		t.Error("expected errors.Is to find the first attempt's error")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Fibonacci performs retries and waits according to the Fibonacci sequence: f(x) = a*Fib(x) + y where x is the
+// number of failed attempts so far (starting at 1), so the waits grow as a*1, a*1, a*2, a*3, a*5, a*8, ... plus y.
+// This grows more gently than Exponential with base 2.
+type Fibonacci struct {
+	// Times is the maximum number of times to execute the back-off, or Unlimited to never run out
+	Times uint
+
+	// Scaling is multiplication factor, this controls the units of the sequence
+	Scaling time.Duration
+
+	// YOffset is added to each wait
+	YOffset time.Duration
+
+	// MaxAttemptWaitTime The maximum amount of time to wait for a particular attempt (does not account for total time), regardless of the sequence (leave as 0 to ignore)
+	MaxAttemptWaitTime time.Duration
+
+	// Jitter randomizes each wait after MaxAttemptWaitTime is applied (leave as the zero value to not randomize)
+	Jitter Jitter
+
+	// Clock is used to wait between attempts. Leave nil to use the system clock.
+	Clock Clock
+
+	// AttemptTimeout limits how long each attempt may run when using ThisContext (leave as 0 to ignore)
+	AttemptTimeout time.Duration
+
+	// AttemptTimeoutGrowth multiplies the AttemptTimeout after each attempt, e.g. 2 doubles it each try (leave as 0 to not grow)
+	AttemptTimeoutGrowth float64
+
+	// MaxElapsedTime stops retrying once the next wait would end later than this long after the first attempt (leave as 0 to ignore)
+	MaxElapsedTime time.Duration
+
+	// Deadline is what NewWithContext does when the next wait would end past the context's deadline
+	Deadline DeadlineMode
+
+	// ErrorList creates the list that records the errors, e.g. RingErrors to keep only the latest. Leave nil to keep every error.
+	ErrorList ErrorListFactory
+}
+
+// New creates a new Fibonacci back-off with its own count of attempts
+func (l Fibonacci) New() Service {
+	return &maxExponentialService{
+		config: Exponential{
+			Times:                l.Times,
+			MaxAttemptWaitTime:   l.MaxAttemptWaitTime,
+			Jitter:               l.Jitter,
+			Clock:                l.Clock,
+			AttemptTimeout:       l.AttemptTimeout,
+			AttemptTimeoutGrowth: l.AttemptTimeoutGrowth,
+			MaxElapsedTime:       l.MaxElapsedTime,
+			Deadline:             l.Deadline,
+			ErrorList:            l.ErrorList,
+		},
+		equation: func(triesSoFar uint) time.Duration {
+			return saturatingAdd(saturatingMul(fibonacci(triesSoFar), l.Scaling), l.YOffset)
+		},
+	}
+}
+
+// NewWithContext creates a new Fibonacci back-off that stops once ctx is done
+func (l Fibonacci) NewWithContext(ctx context.Context) Service {
+	return &maxExponentialContextService{
+		maxExponentialService: *l.New().(*maxExponentialService),
+		ctx:                   ctx,
+	}
+}
+
+// fibonacci returns the nth Fibonacci number, where fibonacci(1) and fibonacci(2) are 1. Saturates instead of
+// overflowing.
+func fibonacci(n uint) time.Duration {
+	var previous, current time.Duration = 0, 1
+	if n == 0 {
+		return 1
+	}
+	for i := uint(1); i < n; i++ {
+		if current == math.MaxInt64 {
+			// saturated, it will not grow any further
+			break
+		}
+		previous, current = current, saturatingAdd(previous, current)
+	}
+	return current
+}
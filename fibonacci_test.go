@@ -0,0 +1,129 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFibonacci_New(t *testing.T) {
+	cases := map[string]struct {
+		cfg      Fibonacci
+		expected []time.Duration
+	}{
+		"1sec*Fib(x)": {
+			cfg: Fibonacci{
+				Scaling: 1 * time.Second,
+			},
+			expected: []time.Duration{1 * time.Second, 1 * time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, 8 * time.Second, 13 * time.Second},
+		},
+		"10sec*Fib(x) + 1sec": {
+			cfg: Fibonacci{
+				Scaling: 10 * time.Second,
+				YOffset: 1 * time.Second,
+			},
+			expected: []time.Duration{11 * time.Second, 11 * time.Second, 21 * time.Second, 31 * time.Second, 51 * time.Second},
+		},
+		"10sec*Fib(x) (limit 30)": {
+			cfg: Fibonacci{
+				Scaling:            10 * time.Second,
+				MaxAttemptWaitTime: 30 * time.Second,
+			},
+			expected: []time.Duration{10 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second},
+		},
+		"10sec*Fib(x) (equal jitter)": {
+			cfg: Fibonacci{
+				Scaling: 10 * time.Second,
+				Jitter:  Jitter{Mode: JitterEqual, Source: fixedRandom(0)},
+			},
+			expected: []time.Duration{5 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 25 * time.Second},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			svc := c.cfg.New().(*maxExponentialService)
+			for i, expected := range c.expected {
+				svc.NotifyRetry()
+				if actual := svc.waitDuration(); actual != expected {
+					t.Errorf(`wait %d: expected duration: %v but got %v`, i+1, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestFibonacci_Saturates(t *testing.T) {
+	svc := Fibonacci{Times: 200, Scaling: time.Hour}.New().(*maxExponentialService)
+	var last time.Duration
+	for i := 0; i < 200; i++ {
+		svc.NotifyRetry()
+		wait := svc.waitDuration()
+		if wait < last {
+			t.Fatalf(`wait %d: expected the wait to never shrink, but went from %v to %v`, i+1, last, wait)
+		}
+		last = wait
+	}
+	if last != math.MaxInt64 {
+		t.Errorf(`expected the wait to stop at the largest duration, but got %v`, last)
+	}
+}
+
+func TestFibonacci_Times(t *testing.T) {
+	tries := 0
+	err := How(Fibonacci{Times: 4, Clock: retrytest.NewAutoClock(time.Now())}.New()).This(func(controller ServiceController) error {
+		tries++
+		return errors.New("boom")
+	})
+	if tries != 4 {
+		t.Errorf(`expected 4 tries, but got %d`, tries)
+	}
+	if len(err.Errors()) != 4 {
+		t.Errorf(`expected 4 errors, but got %d`, len(err.Errors()))
+	}
+}
+
+func TestFibonacci_MaxElapsedTime(t *testing.T) {
+	clock := retrytest.NewAutoClock(time.Now())
+	tries := 0
+	err := How(Fibonacci{Times: Unlimited, Scaling: time.Second, MaxElapsedTime: 10 * time.Second, Clock: clock}.New()).This(func(controller ServiceController) error {
+		tries++
+		return errors.New("boom")
+	})
+	// waits of 1, 1, 2 and 3 seconds end 7 seconds in, the next wait of 5 would end past 10
+	if tries != 5 {
+		t.Errorf(`expected 5 tries, but got %d`, tries)
+	}
+	if !errors.Is(err.Reason(), ErrExhausted) {
+		t.Errorf(`expected reason: %v but got %v`, ErrExhausted, err.Reason())
+	}
+}
+
+func TestFibonacci_NewWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	svc := Fibonacci{Times: 4}.NewWithContext(ctx)
+	if !svc.ShouldTry() {
+		t.Fatal(`expected to try before the context is done`)
+	}
+	cancel()
+	if svc.ShouldTry() {
+		t.Error(`expected not to try once the context is done`)
+	}
+}
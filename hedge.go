@@ -0,0 +1,259 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Hedge is a Retrier for reads where tail latency matters. Instead of waiting for an attempt to fail, it starts
+// another if the first has not finished within a hedge delay, up to Attempts running at once. The first attempt to
+// succeed wins and the others are cancelled through their contexts. A failed attempt starts the next one at once.
+//
+// The hedge delay is Delay, or, when Percentile and Latencies are set, that percentile of the recent successful
+// attempts' latencies.
+type Hedge struct {
+	// Attempts is the most attempts that will be started. Leave as 0 to use 2.
+	Attempts uint
+
+	// Delay is how long to wait for an attempt before starting the next one
+	Delay time.Duration
+
+	// Percentile picks the hedge delay from Latencies instead, e.g. 0.95 to hedge attempts slower than 95% of recent
+	// ones (leave as 0 to always use Delay)
+	Percentile float64
+
+	// Latencies records how long successful attempts took. Share it between calls so the Percentile is meaningful.
+	Latencies *LatencyHistory
+
+	// Clock is used to wait for the hedge delay. Leave nil to use the system clock.
+	Clock Clock
+}
+
+// hedgeResult is the outcome of one attempt
+type hedgeResult struct {
+	err      error
+	duration time.Duration
+}
+
+// This invokes the developer's method, starting overlapping attempts as needed
+func (h Hedge) This(test func(controller ServiceController) error) Errorer {
+	return h.ThisContext(context.Background(), func(_ context.Context, controller ServiceController) error {
+		return test(controller)
+	})
+}
+
+// ThisContext invokes the developer's method, starting overlapping attempts as needed. Each attempt's context is
+// cancelled as soon as another attempt succeeds, or ctx is done. Returns nil if an attempt succeeded, use Race to
+// see the errors of the attempts that lost.
+func (h Hedge) ThisContext(ctx context.Context, test func(ctx context.Context, controller ServiceController) error) Errorer {
+	errList, won := h.Race(ctx, test)
+	if won {
+		return nil
+	}
+	return errList
+}
+
+// Race works like ThisContext, but always returns the errors, along with whether an attempt succeeded. It returns as
+// soon as the outcome is known, without waiting for the cancelled attempts to return. Their errors are appended to
+// the list as they do, but Last stays the error that ended the call.
+func (h Hedge) Race(ctx context.Context, test func(ctx context.Context, controller ServiceController) error) (errList Errorer, won bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	clock := clockOrDefault(h.Clock)
+	maxAttempts := h.maxAttempts()
+	delay := h.delay()
+	errorList := newSyncErrorList(newErrorList())
+	controller := &hedgeController{}
+	// buffered so that attempts still running after this returns can report and exit without anyone listening
+	results := make(chan hedgeResult, maxAttempts)
+	startedAt := clock.Now()
+
+	var launched uint
+	var previousErr error
+	var hedgeTimer <-chan time.Time
+	launch := func() {
+		launched++
+		controller := &hedgeAttempt{
+			hedgeController: controller,
+			attempt:         launched,
+			maxAttempts:     maxAttempts,
+			previousErr:     previousErr,
+			startedAt:       startedAt,
+			clock:           clock,
+		}
+		go func() {
+			startedAt := clock.Now()
+			err := test(ctx, controller)
+			if err != nil {
+				errorList.Append(err)
+			}
+			results <- hedgeResult{err: err, duration: clock.Now().Sub(startedAt)}
+		}()
+		hedgeTimer = nil
+		if launched < maxAttempts {
+			hedgeTimer = clock.After(delay)
+		}
+	}
+
+	launch()
+	var finished uint
+	for finished < launched {
+		select {
+		case result := <-results:
+			finished++
+			if result.err == nil {
+				// the winner, cancel the rest
+				if h.Latencies != nil {
+					h.Latencies.Record(result.duration)
+				}
+				errorList.settle(nil, nil)
+				return errorList, true
+			}
+			previousErr = result.err
+			if IsPermanent(result.err) || controller.aborted() {
+				errorList.settle(result.err, ErrAborted)
+				return errorList, false
+			}
+			if launched < maxAttempts {
+				// no point waiting out the delay for an attempt that has already failed
+				launch()
+			}
+		case <-hedgeTimer:
+			launch()
+		case <-ctx.Done():
+			errorList.settle(nil, contextDone(ctx))
+			return errorList, false
+		}
+	}
+	errorList.settle(nil, ErrExhausted)
+	return errorList, false
+}
+
+func (h Hedge) maxAttempts() uint {
+	if h.Attempts == 0 {
+		return 2
+	}
+	return h.Attempts
+}
+
+// delay returns how long to wait for an attempt before starting the next
+func (h Hedge) delay() time.Duration {
+	if h.Percentile > 0 && h.Latencies != nil {
+		if latency, ok := h.Latencies.Percentile(h.Percentile); ok {
+			return latency
+		}
+	}
+	return h.Delay
+}
+
+// hedgeController is shared by every attempt of a hedged call. Abort stops any more attempts from starting.
+type hedgeController struct {
+	abort atomic.Bool
+}
+
+func (c *hedgeController) Abort() {
+	c.abort.Store(true)
+}
+
+func (c *hedgeController) aborted() bool {
+	return c.abort.Load()
+}
+
+// hedgeAttempt is the controller for one attempt of a hedged call
+type hedgeAttempt struct {
+	*hedgeController
+	attempt     uint
+	maxAttempts uint
+	// previousErr is the error of the latest attempt to fail before this one started
+	previousErr error
+	startedAt   time.Time
+	clock       Clock
+}
+
+// Attempt returns the number of this attempt, starting at 1
+func (a *hedgeAttempt) Attempt() uint {
+	return a.attempt
+}
+
+// Elapsed returns how long ago the first attempt started
+func (a *hedgeAttempt) Elapsed() time.Duration {
+	return a.clock.Now().Sub(a.startedAt)
+}
+
+// PreviousError returns the error of the latest attempt to fail before this one started. Earlier attempts may still
+// be running, so this is nil unless one of them failed.
+func (a *hedgeAttempt) PreviousError() error {
+	return a.previousErr
+}
+
+// Remaining returns how many more attempts may be started after this one
+func (a *hedgeAttempt) Remaining() uint {
+	return a.maxAttempts - a.attempt
+}
+
+// LatencyHistory keeps the most recent latencies so percentiles can be taken from them. The zero value is ready to
+// use, and it is safe for concurrent use.
+type LatencyHistory struct {
+	// Size is how many of the most recent latencies to keep. Leave as 0 to keep 1000. Do not change it after first use.
+	Size int
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// Record adds a latency, replacing the oldest one if the history is full
+func (l *LatencyHistory) Record(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	size := l.Size
+	if size <= 0 {
+		size = 1000
+	}
+	if len(l.samples) < size {
+		l.samples = append(l.samples, d)
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % size
+}
+
+// Percentile returns the latency that p of the recorded latencies are at or under, e.g. 0.5 for the median. Returns
+// false if nothing has been recorded.
+func (l *LatencyHistory) Percentile(p float64) (time.Duration, bool) {
+	l.mu.Lock()
+	sorted := append([]time.Duration(nil), l.samples...)
+	l.mu.Unlock()
+	if len(sorted) == 0 {
+		return 0, false
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index], true
+}
@@ -0,0 +1,224 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHedge_SecondAttemptWins ensures that a slow attempt is hedged and cancelled once the hedge wins
+func TestHedge_SecondAttemptWins(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	var attempts int32
+	firstCancelled := make(chan error, 1)
+	done := make(chan Errorer)
+	go func() {
+		done <- Hedge{Attempts: 2, Delay: 50 * time.Millisecond, Clock: clock}.ThisContext(context.Background(), func(ctx context.Context, controller ServiceController) error {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				<-ctx.Done()
+				firstCancelled <- ctx.Err()
+				return ctx.Err()
+			}
+			return nil
+		})
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(50 * time.Millisecond)
+
+	if errList := <-done; errList != nil {
+		t.Errorf(`expected the hedge to succeed, got: "%v"`, errList)
+	}
+	if err := <-firstCancelled; err != context.Canceled {
+		t.Errorf(`expected the losing attempt to be cancelled, got: "%v"`, err)
+	}
+	if attempts != 2 {
+		t.Errorf(`expected 2 attempts, got: %d`, attempts)
+	}
+}
+
+// TestHedge_AllFail ensures that failed attempts start the next one without waiting and every error is kept
+func TestHedge_AllFail(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	errList := Hedge{Attempts: 3, Delay: 1 * time.Hour, Clock: clock}.This(func(controller ServiceController) error {
+		return errors.New("boom")
+	})
+	if errList == nil {
+		t.Fatal("expected an error list")
+	}
+	if len(errList.Errors()) != 3 {
+		t.Errorf(`expected 3 errors, got: %d`, len(errList.Errors()))
+	}
+	if errList.Reason() != ErrExhausted {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrExhausted, errList.Reason())
+	}
+}
+
+// TestHedge_Permanent ensures that a permanent error stops any more attempts from starting
+func TestHedge_Permanent(t *testing.T) {
+	var attempts int32
+	errList := Hedge{Attempts: 3, Delay: 1 * time.Hour}.This(func(controller ServiceController) error {
+		atomic.AddInt32(&attempts, 1)
+		return Permanent(errors.New("bad input"))
+	})
+	if attempts != 1 {
+		t.Errorf(`expected 1 attempt, got: %d`, attempts)
+	}
+	if errList == nil || errList.Reason() != ErrAborted {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrAborted, errList)
+	}
+}
+
+// TestHedge_ContextDone ensures that the caller's context stops every attempt
+func TestHedge_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errList := Hedge{Attempts: 2, Delay: 1 * time.Hour}.ThisContext(ctx, func(ctx context.Context, controller ServiceController) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if errList == nil || !errors.Is(errList.Reason(), ErrContextDone) {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrContextDone, errList)
+	}
+}
+
+// TestHedge_PermanentWhileOthersRun ensures that attempts cancelled by a permanent error still add their errors
+// once they return, without changing the last error
+func TestHedge_PermanentWhileOthersRun(t *testing.T) {
+	permanent := errors.New("bad input")
+	stopped := make(chan struct{})
+	errList := Hedge{Attempts: 2, Delay: 1 * time.Hour, Clock: retrytest.NewAutoClock(time.Now())}.ThisContext(context.Background(), func(ctx context.Context, controller ServiceController) error {
+		if controller.(AttemptInfo).Attempt() == 2 {
+			return Permanent(permanent)
+		}
+		<-ctx.Done()
+		defer close(stopped)
+		return ctx.Err()
+	})
+	<-stopped
+	// the first attempt's error is appended after it returns
+	for len(errList.Errors()) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if !errors.Is(errList.Last(), permanent) {
+		t.Errorf(`expected the last error to stay the permanent one, got: "%v"`, errList.Last())
+	}
+	if !AnyIs(errList, context.Canceled) {
+		t.Errorf(`expected the cancelled attempt's error to be kept, got: %v`, errList.Errors())
+	}
+}
+
+// TestHedge_DoesNotWaitForCancelledAttempts ensures that an attempt ignoring its context does not hold up the call
+func TestHedge_DoesNotWaitForCancelledAttempts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	done := make(chan Errorer)
+	go func() {
+		done <- Hedge{Attempts: 2, Delay: 1 * time.Hour}.ThisContext(ctx, func(ctx context.Context, controller ServiceController) error {
+			<-release
+			return errors.New("too late")
+		})
+	}()
+	select {
+	case errList := <-done:
+		if errList == nil || !errors.Is(errList.Reason(), ErrContextDone) {
+			t.Errorf(`expected reason: "%v" but got: "%v"`, ErrContextDone, errList)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the call to return once the context was done")
+	}
+}
+
+// TestHedge_RaceKeepsLosers ensures that the errors of the attempts that lost can be inspected after a win
+func TestHedge_RaceKeepsLosers(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	firstReturned := make(chan struct{})
+	type outcome struct {
+		errList Errorer
+		won     bool
+	}
+	done := make(chan outcome)
+	go func() {
+		errList, won := Hedge{Attempts: 2, Delay: 50 * time.Millisecond, Clock: clock}.Race(context.Background(), func(ctx context.Context, controller ServiceController) error {
+			if controller.(AttemptInfo).Attempt() == 1 {
+				<-ctx.Done()
+				defer close(firstReturned)
+				return ctx.Err()
+			}
+			return nil
+		})
+		done <- outcome{errList: errList, won: won}
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(50 * time.Millisecond)
+
+	result := <-done
+	if !result.won {
+		t.Fatalf(`expected an attempt to win, got: "%v"`, result.errList)
+	}
+	if result.errList.Reason() != nil {
+		t.Errorf(`expected no reason, got: "%v"`, result.errList.Reason())
+	}
+	<-firstReturned
+	for len(result.errList.Errors()) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if !errors.Is(result.errList.Errors()[0], context.Canceled) {
+		t.Errorf(`expected the loser to have been cancelled, got: "%v"`, result.errList.Errors()[0])
+	}
+}
+
+// TestHedge_PercentileDelay ensures that the hedge delay comes from the recent latencies
+func TestHedge_PercentileDelay(t *testing.T) {
+	latencies := &LatencyHistory{}
+	for i := 1; i <= 10; i++ {
+		latencies.Record(time.Duration(i) * 10 * time.Millisecond)
+	}
+	clock := retrytest.NewClock(time.Now())
+	errList := Hedge{Delay: 1 * time.Second, Percentile: 0.9, Latencies: latencies, Clock: clock}.This(alwaysSucceed)
+	if errList != nil {
+		t.Fatalf(`expected success, got: "%v"`, errList)
+	}
+	waits := clock.Waits()
+	if len(waits) != 1 || waits[0] != 90*time.Millisecond {
+		t.Errorf(`expected to hedge after 90ms, got: %v`, waits)
+	}
+}
+
+func TestLatencyHistory(t *testing.T) {
+	latencies := &LatencyHistory{Size: 3}
+	if _, ok := latencies.Percentile(0.5); ok {
+		t.Error("expected no percentile without any latencies")
+	}
+	for _, d := range []time.Duration{1, 2, 3, 4, 5} {
+		latencies.Record(d * time.Second)
+	}
+	cases := map[float64]time.Duration{
+		0:   3 * time.Second,
+		0.5: 4 * time.Second,
+		1:   5 * time.Second,
+	}
+	for p, expected := range cases {
+		if actual, _ := latencies.Percentile(p); actual != expected {
+			t.Errorf(`expected percentile %v to be %v, got: %v`, p, expected, actual)
+		}
+	}
+}
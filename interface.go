@@ -14,6 +14,11 @@
 
 package retry
 
+import (
+	"context"
+	"time"
+)
+
 // Retrier is how a task should be retried
 type Retrier interface {
 	// This attempts to retry the function passed into it.
@@ -22,6 +27,19 @@ type Retrier interface {
 	// @param test the function to test for failure. Return an error to indicate a failure, nil to indicate success
 	// @return nil if the function (eventually) succeeded, an Errorer containing a reference to the errors if not
 	This(test func(controller ServiceController) error) Errorer
+
+	// ThisContext works like This, but passes ctx into each attempt. Once ctx is done no more attempts are made and any
+	// Yield in progress is cut short.
+	// @param ctx governs all attempts and waits
+	// @param test the function to test for failure. Return an error to indicate a failure, nil to indicate success
+	// @return nil if the function (eventually) succeeded, an Errorer containing a reference to the errors if not
+	ThisContext(ctx context.Context, test func(ctx context.Context, controller ServiceController) error) Errorer
+}
+
+// Factory creates a new Service, with its own state, for each thing to retry. Exponential, ExpBase2 and MaxAttempts
+// are all Factories.
+type Factory interface {
+	New() Service
 }
 
 // Service keeps the state of what to do when retrying things
@@ -45,6 +63,47 @@ type Service interface {
 	NewErrorList() ErrorAppender
 }
 
+// ContextYielder is optionally implemented by a Service whose Yield can be cut short by a context. Services that do
+// not implement this are still cut short by ThisContext, but their Yield keeps running in the background until done.
+type ContextYielder interface {
+	// YieldContext works like Yield, but returns as soon as ctx is done
+	YieldContext(ctx context.Context)
+}
+
+// AttemptTimeouter is optionally implemented by a Service that limits how long each attempt may run. Each attempt
+// is given a context derived from the caller's that is cancelled after this duration.
+type AttemptTimeouter interface {
+	// AttemptTimeout returns how long the next attempt may run, or 0 for no limit
+	AttemptTimeout() time.Duration
+}
+
+// Reasoner is optionally implemented by a Service that can explain why ShouldTry returned false. Services that do
+// not implement this are assumed to have run out of attempts.
+type Reasoner interface {
+	// Reason returns ErrExhausted, ErrAborted, an error matching ErrContextDone or your own error explaining why no
+	// more attempts should be made. Returns nil while ShouldTry is still true.
+	Reason() error
+}
+
+// WaitPlanner is optionally implemented by a Service that can say how long its next Yield will wait. This is passed
+// to Observers as Event.Wait.
+type WaitPlanner interface {
+	// NextWait returns how long the next Yield will wait. Calling it more than once before Yield returns the same value.
+	NextWait() time.Duration
+}
+
+// AttemptGate is optionally implemented by a Service that may refuse to let an attempt happen, e.g. a circuit breaker.
+type AttemptGate interface {
+	// AllowAttempt is called before each attempt. Return nil to allow it, or an error to stop retrying without making
+	// the attempt. The error is recorded and becomes the Reason.
+	AllowAttempt() error
+
+	// AttemptDone is called with the outcome of each attempt that was allowed, nil for success. If the caller's
+	// context ended during the attempt and err came from it, err also matches ErrContextDone, so that the caller going
+	// away can be told apart from the dependency failing.
+	AttemptDone(err error)
+}
+
 // ServiceController controls the retry service
 type ServiceController interface {
 	// Abort informs the service to no longer perform retries. Calling multiple times should have no additional effects.
@@ -53,6 +112,38 @@ type ServiceController interface {
 	Abort()
 }
 
+// RetryAfterSetter is optionally implemented by a ServiceController whose service can be asked to wait longer before
+// the next attempt. The controllers of all built-in Services implement it.
+type RetryAfterSetter interface {
+	// RetryAfter asks the service to wait at least d before the next attempt, e.g. because a server said when to come
+	// back. The wait is still limited by the service's maximum wait, if it has one, and by its context.
+	RetryAfter(d time.Duration)
+}
+
+// AttemptInfo is optionally implemented by a ServiceController to tell the function being retried about the attempt
+// in progress, e.g. to add an attempt number header or switch replicas. The controllers of all built-in Services and
+// Retriers implement it.
+type AttemptInfo interface {
+	// Attempt returns the number of the attempt in progress, starting at 1
+	Attempt() uint
+
+	// Elapsed returns how long ago the first attempt started
+	Elapsed() time.Duration
+
+	// PreviousError returns the error of the previous attempt, or nil during the first
+	PreviousError() error
+
+	// Remaining returns how many more attempts may follow this one, or Unlimited if there is no limit on the number of
+	// attempts. Other limits, such as a context or MaxElapsedTime, may still stop retrying sooner.
+	Remaining() uint
+}
+
+// RetryAfterer may be implemented by an error returned from an attempt to say how long to wait before trying again.
+// It is found with errors.As and has the same effect as calling RetryAfterSetter.RetryAfter on the controller.
+type RetryAfterer interface {
+	RetryAfter() time.Duration
+}
+
 // Errorer contains the errors returned by the
 type Errorer interface {
 	// Errors gets every error returned by each try
@@ -63,6 +154,13 @@ type Errorer interface {
 
 	// Last gets the very last error message generated by a failure, or nil if no error was encountered
 	Last() error
+
+	// Reason returns why retrying stopped: ErrExhausted, ErrAborted, or an error matching ErrContextDone
+	Reason() error
+
+	// Unwrap returns every error followed by the Reason, so that errors.Is and errors.As search all of them, the same
+	// as with errors.Join
+	Unwrap() []error
 }
 
 // ErrorAppender allows the Errorer to be a facade so that callers of the retry methods don't see the Append method. This is only used internally, but if you wish to provide your own, custom Errorer, you'll need to satisfy this interface
@@ -71,4 +169,7 @@ type ErrorAppender interface {
 
 	// Append adds an error to the list of errors
 	Append(err error)
+
+	// SetReason records why retrying stopped
+	SetReason(reason error)
 }
@@ -0,0 +1,87 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// JitterMode selects how a wait duration is randomized
+type JitterMode int
+
+const (
+	// JitterNone uses the wait duration exactly as calculated. This is the default.
+	JitterNone JitterMode = iota
+
+	// JitterFull picks a random wait between 0 and the calculated wait
+	JitterFull
+
+	// JitterEqual keeps half of the calculated wait and randomizes the other half
+	JitterEqual
+
+	// JitterProportional randomizes the calculated wait by ±Factor of itself, e.g. 0.2 for ±20%
+	JitterProportional
+)
+
+// RandomSource provides random numbers in the range [0.0, 1.0). *rand.Rand satisfies this interface.
+type RandomSource interface {
+	Float64() float64
+}
+
+// Jitter spreads out the wait durations so that many callers failing at the same time do not all retry at the same
+// time. It is applied after MaxAttemptWaitTime has constrained the wait.
+type Jitter struct {
+	// Mode is the jitter strategy to use
+	Mode JitterMode
+
+	// Factor is the proportion of the wait to randomize by when Mode is JitterProportional. Ignored otherwise.
+	Factor float64
+
+	// Source is where random numbers come from. Leave nil to use math/rand's default (concurrency-safe) source. If
+	// you provide your own, it must be safe to use from every Service created by this configuration.
+	Source RandomSource
+}
+
+// apply randomizes the waitFor duration according to the jitter mode
+func (j Jitter) apply(waitFor time.Duration) time.Duration {
+	switch j.Mode {
+	case JitterFull:
+		return time.Duration(j.random() * float64(waitFor))
+	case JitterEqual:
+		half := waitFor / 2
+		return waitFor - half + time.Duration(j.random()*float64(half))
+	case JitterProportional:
+		// scale by a random value in [1-Factor, 1+Factor)
+		scaled := float64(waitFor) * (1.0 + j.Factor*(2.0*j.random()-1.0))
+		if scaled < 0 {
+			return 0
+		}
+		if scaled >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+		return time.Duration(scaled)
+	default:
+		return waitFor
+	}
+}
+
+func (j Jitter) random() float64 {
+	if j.Source == nil {
+		return rand.Float64()
+	}
+	return j.Source.Float64()
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+// fixedRandom always returns the same "random" number so that jitter can be tested
+type fixedRandom float64
+
+func (f fixedRandom) Float64() float64 {
+	return float64(f)
+}
+
+// TestJitter_apply tests the range of each jitter strategy using the extremes of the random source
+func TestJitter_apply(t *testing.T) {
+	cases := map[string]struct {
+		jitter   Jitter
+		waitFor  time.Duration
+		expected time.Duration
+	}{
+		"none": {
+			jitter:   Jitter{Source: fixedRandom(0.5)},
+			waitFor:  10 * time.Second,
+			expected: 10 * time.Second,
+		},
+		"full, low": {
+			jitter:   Jitter{Mode: JitterFull, Source: fixedRandom(0)},
+			waitFor:  10 * time.Second,
+			expected: 0,
+		},
+		"full, middle": {
+			jitter:   Jitter{Mode: JitterFull, Source: fixedRandom(0.25)},
+			waitFor:  10 * time.Second,
+			expected: 2500 * time.Millisecond,
+		},
+		"equal, low": {
+			jitter:   Jitter{Mode: JitterEqual, Source: fixedRandom(0)},
+			waitFor:  10 * time.Second,
+			expected: 5 * time.Second,
+		},
+		"equal, middle": {
+			jitter:   Jitter{Mode: JitterEqual, Source: fixedRandom(0.5)},
+			waitFor:  10 * time.Second,
+			expected: 7500 * time.Millisecond,
+		},
+		"proportional, low": {
+			jitter:   Jitter{Mode: JitterProportional, Factor: 0.2, Source: fixedRandom(0)},
+			waitFor:  10 * time.Second,
+			expected: 8 * time.Second,
+		},
+		"proportional, middle": {
+			jitter:   Jitter{Mode: JitterProportional, Factor: 0.2, Source: fixedRandom(0.5)},
+			waitFor:  10 * time.Second,
+			expected: 10 * time.Second,
+		},
+		"proportional, never negative": {
+			jitter:   Jitter{Mode: JitterProportional, Factor: 2, Source: fixedRandom(0)},
+			waitFor:  10 * time.Second,
+			expected: 0,
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			actual := c.jitter.apply(c.waitFor)
+			if actual != c.expected {
+				t.Errorf(`expected duration: %v but got %v`, c.expected, actual)
+			}
+		})
+	}
+}
+
+// TestJitter_DefaultSource ensures that the default source stays within the bounds of the strategy
+func TestJitter_DefaultSource(t *testing.T) {
+	j := Jitter{Mode: JitterFull}
+	for i := 0; i < 100; i++ {
+		actual := j.apply(10 * time.Second)
+		if actual < 0 || actual >= 10*time.Second {
+			t.Fatalf(`expected duration within [0s, 10s) but got %v`, actual)
+		}
+	}
+}
+
+// TestExpBase2_Jitter ensures the jitter is applied after the MaxAttemptWaitTime
+func TestExpBase2_Jitter(t *testing.T) {
+	svc := ExpBase2{
+		Scaling:            10 * time.Second,
+		MaxAttemptWaitTime: 30 * time.Second,
+		Jitter:             Jitter{Mode: JitterEqual, Source: fixedRandom(0)},
+	}.New().(*maxExponentialService)
+	expected := []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second, 15 * time.Second}
+	for i := range expected {
+		svc.NotifyRetry()
+		actual := svc.waitDuration()
+		if actual != expected[i] {
+			t.Errorf(`expected duration: %v but got %v`, expected[i], actual)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLinear_New(t *testing.T) {
+	cases := map[string]struct {
+		cfg      Linear
+		expected []time.Duration
+	}{
+		"constant": {
+			cfg: Linear{
+				Initial: 5 * time.Second,
+			},
+			expected: []time.Duration{5 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		"1sec + x*2sec": {
+			cfg: Linear{
+				Initial: 1 * time.Second,
+				Step:    2 * time.Second,
+			},
+			expected: []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second, 7 * time.Second, 9 * time.Second},
+		},
+		"x*10sec (limit 25)": {
+			cfg: Linear{
+				Step:               10 * time.Second,
+				MaxAttemptWaitTime: 25 * time.Second,
+			},
+			expected: []time.Duration{0, 10 * time.Second, 20 * time.Second, 25 * time.Second, 25 * time.Second},
+		},
+		"10sec + x*10sec (full jitter)": {
+			cfg: Linear{
+				Initial: 10 * time.Second,
+				Step:    10 * time.Second,
+				Jitter:  Jitter{Mode: JitterFull, Source: fixedRandom(0.5)},
+			},
+			expected: []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			svc := c.cfg.New().(*maxExponentialService)
+			for i, expected := range c.expected {
+				svc.NotifyRetry()
+				if actual := svc.waitDuration(); actual != expected {
+					t.Errorf(`wait %d: expected duration: %v but got %v`, i+1, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestLinear_Saturates(t *testing.T) {
+	svc := Linear{Initial: time.Hour, Step: math.MaxInt64 / 2}.New().(*maxExponentialService)
+	for i := 0; i < 4; i++ {
+		svc.NotifyRetry()
+	}
+	if actual := svc.waitDuration(); actual != math.MaxInt64 {
+		t.Errorf(`expected the wait to stop at the largest duration, but got %v`, actual)
+	}
+}
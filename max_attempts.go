@@ -18,19 +18,34 @@ import "time"
 
 // MaxAttempts will try a function X number of times, waiting WaitFor in between each failed attempt
 type MaxAttempts struct {
-	// MaxAttempts is the maximum number of retries to allow before returning a failure
+	// MaxAttempts is the maximum number of retries to allow before returning a failure, or Unlimited to never run out
 	Times uint
 	// WaitFor is the time to wait between failures
 	WaitFor time.Duration
+	// Clock is used to wait between attempts. Leave nil to use the system clock.
+	Clock Clock
+	// AttemptTimeout limits how long each attempt may run when using ThisContext (leave as 0 to ignore)
+	AttemptTimeout time.Duration
+	// AttemptTimeoutGrowth multiplies the AttemptTimeout after each attempt, e.g. 2 doubles it each try (leave as 0 to not grow)
+	AttemptTimeoutGrowth float64
+	// MaxElapsedTime stops retrying once the next wait would end later than this long after the first attempt (leave as 0 to ignore)
+	MaxElapsedTime time.Duration
+	// ErrorList creates the list that records the errors, e.g. RingErrors to keep only the latest. Leave nil to keep every error.
+	ErrorList ErrorListFactory
 }
 
 // New creates a new MaxAttempts. New is needed to create a counter state required for this invocation
 func (l MaxAttempts) New() Service {
 	return &maxExponentialService{
 		config: Exponential{
-			Times:   l.Times,
-			YOffset: l.WaitFor,
-			Base:    0,
+			Times:                l.Times,
+			YOffset:              l.WaitFor,
+			Base:                 0,
+			Clock:                l.Clock,
+			AttemptTimeout:       l.AttemptTimeout,
+			AttemptTimeoutGrowth: l.AttemptTimeoutGrowth,
+			MaxElapsedTime:       l.MaxElapsedTime,
+			ErrorList:            l.ErrorList,
 		},
 	}
 }
@@ -41,16 +41,96 @@ func (l ExpBase2) NewWithContext(ctx context.Context) Service {
 type maxExponentialContextService struct {
 	maxExponentialService
 	ctx context.Context
+
+	// deadlineWait is the planned wait after fitting it before the deadline, if deadlinePlanned is true
+	deadlineWait    time.Duration
+	deadlinePlanned bool
+	// shortened is true if the planned wait was shortened to fit one last attempt
+	shortened bool
+	// lastAttemptMade is true once the attempt after a shortened wait is over
+	lastAttemptMade bool
+}
+
+// ShouldTry will execute unless all of our retries allotted have failed, the context is done, or the next wait
+// would pass the context's deadline
+func (c *maxExponentialContextService) ShouldTry() bool {
+	return c.ctx.Err() == nil && c.maxExponentialService.ShouldTry() && !c.pastDeadline()
+}
+
+// Reason explains why ShouldTry is false
+func (c *maxExponentialContextService) Reason() error {
+	if c.aborted {
+		return ErrAborted
+	}
+	if c.ctx.Err() != nil {
+		return contextDone(c.ctx)
+	}
+	if reason := c.maxExponentialService.Reason(); reason != nil {
+		return reason
+	}
+	if c.pastDeadline() {
+		return ErrDeadline
+	}
+	return nil
+}
+
+// pastDeadline is true if there is no point waiting, as the wait would end past the context's deadline
+func (c *maxExponentialContextService) pastDeadline() bool {
+	deadline, ok := c.ctx.Deadline()
+	if !ok || c.yielded {
+		return false
+	}
+	if c.config.Deadline == DeadlineLastAttempt && !c.lastAttemptMade {
+		// the wait will be shortened instead
+		return false
+	}
+	return c.Clock().Now().Add(c.maxExponentialService.NextWait()).After(deadline)
+}
+
+// NextWait returns how long the next Yield will wait, shortened to fit one last attempt if configured to
+func (c *maxExponentialContextService) NextWait() time.Duration {
+	wait := c.maxExponentialService.NextWait()
+	deadline, ok := c.ctx.Deadline()
+	if !ok || c.config.Deadline != DeadlineLastAttempt {
+		return wait
+	}
+	if !c.deadlinePlanned {
+		c.deadlineWait, c.shortened = fitDeadline(wait, c.AttemptTimeout(), c.Clock().Now(), deadline)
+		c.deadlinePlanned = true
+	}
+	return c.deadlineWait
+}
+
+// NotifyRetry counts the attempt, noting if it was the last one to fit before the deadline
+func (c *maxExponentialContextService) NotifyRetry() {
+	if c.shortened {
+		c.lastAttemptMade = true
+	}
+	c.maxExponentialService.NotifyRetry()
+	c.deadlinePlanned = false
+	c.shortened = false
+}
+
+// done is closed once the context is done
+func (c *maxExponentialContextService) done() <-chan struct{} {
+	return c.ctx.Done()
 }
 
 // Wait will cause go to sleep for the WaitFor
 func (c *maxExponentialContextService) Yield() {
-	waitFor := c.waitDuration()
+	c.YieldContext(context.Background())
+}
+
+// YieldContext will cause go to sleep for the WaitFor, or until either the service's context or ctx is done
+func (c *maxExponentialContextService) YieldContext(ctx context.Context) {
+	waitFor := c.NextWait()
 	select {
 	case <-c.ctx.Done():
-		// context is done, abort, never yield
-		c.Abort()
-	case <-time.After(waitFor):
+		// context is done, never yield, ShouldTry will now return false
+	case <-ctx.Done():
+		// the caller's context is done, the caller will stop
+	case <-c.Clock().After(waitFor):
 		// time expired, ok to proceed
 	}
+	c.yielded = true
 }
@@ -16,6 +16,8 @@ package retry
 
 import (
 	"context"
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
 	"testing"
 	"time"
 )
@@ -25,52 +27,124 @@ func TestMaxExponentialWithContext_New(t *testing.T) {
 	cases := map[string]struct {
 		cfg         Exponential
 		ctxDuration time.Duration
-		expected    []time.Duration
+		yields      int
 	}{
 		"linear": {
 			cfg: Exponential{
 				Base:    0,
-				YOffset: 10 * time.Microsecond,
+				YOffset: 40 * time.Millisecond,
 			},
-			ctxDuration: 31 * time.Microsecond,
-			expected:    []time.Duration{10 * time.Microsecond, 10 * time.Microsecond, 10 * time.Microsecond},
+			ctxDuration: 50 * time.Millisecond,
+			yields:      4,
 		},
-		"exponential: 10us*2^x + 10us (limit 50 with max exec context of 205ms)": {
+		"exponential: 20ms*2^x": {
 			cfg: Exponential{
+				Base:    2,
+				Scaling: 20 * time.Millisecond,
+			},
+			ctxDuration: 50 * time.Millisecond,
+			yields:      4,
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), c.ctxDuration)
+			defer cancel()
+			svc := c.cfg.NewWithContext(ctx).(*maxExponentialContextService)
+			var planned time.Duration
+			startAt := time.Now()
+			for i := 0; i < c.yields; i++ {
+				svc.NotifyRetry()
+				planned += svc.NextWait()
+				svc.Yield()
+			}
+			elapsed := time.Since(startAt)
+			if elapsed < c.ctxDuration {
+				t.Errorf(`expected to yield until the context timed out after %v, but only yielded %v`, c.ctxDuration, elapsed)
+			}
+			if elapsed >= planned {
+				t.Errorf(`expected the context to cut the %v of waits short, but yielded %v`, planned, elapsed)
+			}
+			if !errors.Is(svc.Reason(), context.DeadlineExceeded) {
+				t.Errorf(`expected the context's deadline to be the reason, but got %v`, svc.Reason())
+			}
+		})
+	}
+}
+
+// TestMaxExponentialWithContext_FakeClock ensures that the exact waits are yielded until the context ends
+func TestMaxExponentialWithContext_FakeClock(t *testing.T) {
+	cases := map[string]struct {
+		cfg      Exponential
+		expected []time.Duration
+	}{
+		"linear": {
+			cfg: Exponential{
+				Times:   10,
+				Base:    0,
+				YOffset: 10 * time.Second,
+			},
+			expected: []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second},
+		},
+		"exponential: 10s*2^x + 10s (limit 50 with 4 waits before the context ends)": {
+			cfg: Exponential{
+				Times:              10,
 				Base:               2,
-				Scaling:            10 * time.Microsecond,
-				YOffset:            10 * time.Microsecond,
-				MaxAttemptWaitTime: 50 * time.Microsecond,
+				Scaling:            10 * time.Second,
+				YOffset:            10 * time.Second,
+				MaxAttemptWaitTime: 50 * time.Second,
 			},
-			ctxDuration: 205 * time.Microsecond,
-			expected:    []time.Duration{20 * time.Microsecond, 30 * time.Microsecond, 50 * time.Microsecond, 50 * time.Microsecond},
+			expected: []time.Duration{20 * time.Second, 30 * time.Second, 50 * time.Second, 50 * time.Second},
 		},
-		"exponential: 10us*2^x + 10us (limit 50 with max exec context of 105ms)": {
+		"exponential: 10s*2^x + 10s (limit 50 with 3 waits before the context ends)": {
 			cfg: Exponential{
+				Times:              10,
 				Base:               2,
-				Scaling:            10 * time.Microsecond,
-				YOffset:            10 * time.Microsecond,
-				MaxAttemptWaitTime: 50 * time.Microsecond,
+				Scaling:            10 * time.Second,
+				YOffset:            10 * time.Second,
+				MaxAttemptWaitTime: 50 * time.Second,
 			},
-			ctxDuration: 105 * time.Microsecond,
-			expected:    []time.Duration{20 * time.Microsecond, 30 * time.Microsecond, 50 * time.Microsecond},
+			expected: []time.Duration{20 * time.Second, 30 * time.Second, 50 * time.Second},
 		},
 	}
 
 	for caseName, c := range cases {
 		t.Run(caseName, func(t *testing.T) {
-			ctx, cancel := context.WithTimeout(context.Background(), c.ctxDuration)
-			svcInterface := c.cfg.NewWithContext(ctx)
-			svc := svcInterface.(*maxExponentialContextService)
-			startAt := time.Now()
-			for i := 0; i < len(c.expected); i++ {
-				svc.Yield()
+			clock := retrytest.NewClock(time.Now())
+			cfg := c.cfg
+			cfg.Clock = clock
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			svc := cfg.NewWithContext(ctx).(*maxExponentialContextService)
+
+			// yield one more time than expected, the context will end during the last yield
+			yielded := make(chan struct{})
+			expected := c.expected
+			go func() {
+				for i := 0; i <= len(expected); i++ {
+					svc.NotifyRetry()
+					svc.Yield()
+					yielded <- struct{}{}
+				}
+			}()
+			for _, waitFor := range c.expected {
+				clock.BlockUntil(1)
+				clock.Advance(waitFor)
+				<-yielded
 			}
+			clock.BlockUntil(1)
 			cancel()
-			endAt := time.Now()
-			expectedEndAt := startAt.Add(c.ctxDuration)
-			if expectedEndAt.Add(1 * time.Microsecond).After(endAt) {
-				t.Errorf(`exceeded context timeout, over by %v`, endAt.Sub(expectedEndAt))
+			<-yielded
+
+			actual := clock.Waits()
+			for i := range c.expected {
+				if c.expected[i] != actual[i] {
+					t.Errorf(`expected duration: %v but got %v`, c.expected[i], actual[i])
+				}
+			}
+			if svc.ShouldTry() {
+				t.Error("expected the context to abort the retries, but it did not")
 			}
 		})
 	}
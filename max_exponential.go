@@ -15,18 +15,26 @@
 package retry
 
 import (
+	"context"
 	"math"
 	"time"
 )
 
+// Unlimited can be used as Times to keep trying until the attempt succeeds, is aborted, returns a permanent error or
+// the context is done
+const Unlimited = ^uint(0)
+
 // Exponential performs retries and waits according to an exponential equation: f(x) = a*B^x + y where B is the base, x is the number of retries (starting at 0) and y is the offset time (cannot be less than 0)
 type Exponential struct {
-	// Times is the maximum number of times to execute the back-off
+	// Times is the maximum number of times to execute the back-off, or Unlimited to never run out
 	Times uint
 
 	// Base is the base of the exponent
 	Base time.Duration
 
+	// Multiplier is a fractional base, e.g. 1.5 grows each wait by half. When set, it is used instead of Base.
+	Multiplier float64
+
 	// YOffset is the y-component of the exponential equation
 	YOffset time.Duration
 
@@ -35,6 +43,27 @@ type Exponential struct {
 
 	// MaxAttemptWaitTime The maximum amount of time to wait for a particular attempt (does not account for total time), regardless of the exponential equation (leave as 0 to ignore)
 	MaxAttemptWaitTime time.Duration
+
+	// Jitter randomizes each wait after MaxAttemptWaitTime is applied (leave as the zero value to not randomize)
+	Jitter Jitter
+
+	// Clock is used to wait between attempts. Leave nil to use the system clock.
+	Clock Clock
+
+	// AttemptTimeout limits how long each attempt may run when using ThisContext (leave as 0 to ignore)
+	AttemptTimeout time.Duration
+
+	// AttemptTimeoutGrowth multiplies the AttemptTimeout after each attempt, e.g. 2 doubles it each try (leave as 0 to not grow)
+	AttemptTimeoutGrowth float64
+
+	// MaxElapsedTime stops retrying once the next wait would end later than this long after the first attempt (leave as 0 to ignore)
+	MaxElapsedTime time.Duration
+
+	// Deadline is what NewWithContext does when the next wait would end past the context's deadline
+	Deadline DeadlineMode
+
+	// ErrorList creates the list that records the errors, e.g. RingErrors to keep only the latest. Leave nil to keep every error.
+	ErrorList ErrorListFactory
 }
 
 func (l Exponential) New() Service {
@@ -45,7 +74,7 @@ func (l Exponential) New() Service {
 
 // ExpBase2 configures the Exponential but with base 2 instead of an arbitrary base.
 type ExpBase2 struct {
-	// Times is the maximum number of times to execute the back-off
+	// Times is the maximum number of times to execute the back-off, or Unlimited to never run out
 	Times uint
 
 	// YOffset is the y-component of the exponential equation
@@ -56,63 +85,254 @@ type ExpBase2 struct {
 
 	// MaxAttemptWaitTime The maximum amount of time to wait for a particular attempt (does not account for total time), regardless of the exponential equation (leave as 0 to ignore)
 	MaxAttemptWaitTime time.Duration
+
+	// Jitter randomizes each wait after MaxAttemptWaitTime is applied (leave as the zero value to not randomize)
+	Jitter Jitter
+
+	// Clock is used to wait between attempts. Leave nil to use the system clock.
+	Clock Clock
+
+	// AttemptTimeout limits how long each attempt may run when using ThisContext (leave as 0 to ignore)
+	AttemptTimeout time.Duration
+
+	// AttemptTimeoutGrowth multiplies the AttemptTimeout after each attempt, e.g. 2 doubles it each try (leave as 0 to not grow)
+	AttemptTimeoutGrowth float64
+
+	// MaxElapsedTime stops retrying once the next wait would end later than this long after the first attempt (leave as 0 to ignore)
+	MaxElapsedTime time.Duration
+
+	// Deadline is what NewWithContext does when the next wait would end past the context's deadline
+	Deadline DeadlineMode
+
+	// ErrorList creates the list that records the errors, e.g. RingErrors to keep only the latest. Leave nil to keep every error.
+	ErrorList ErrorListFactory
 }
 
 func (l ExpBase2) New() Service {
 	return &maxExponentialService{
 		config: Exponential{
-			Times:              l.Times,
-			YOffset:            l.YOffset,
-			Scaling:            l.Scaling,
-			MaxAttemptWaitTime: l.MaxAttemptWaitTime,
-			Base:               2,
+			Times:                l.Times,
+			YOffset:              l.YOffset,
+			Scaling:              l.Scaling,
+			MaxAttemptWaitTime:   l.MaxAttemptWaitTime,
+			Jitter:               l.Jitter,
+			Clock:                l.Clock,
+			AttemptTimeout:       l.AttemptTimeout,
+			AttemptTimeoutGrowth: l.AttemptTimeoutGrowth,
+			MaxElapsedTime:       l.MaxElapsedTime,
+			Deadline:             l.Deadline,
+			ErrorList:            l.ErrorList,
+			Base:                 2,
 		},
 	}
 }
 
 type maxExponentialService struct {
-	config     Exponential
+	config Exponential
+	// equation replaces the exponential equation when set, e.g. for Fibonacci. It is given the number of tries so far.
+	equation   func(triesSoFar uint) time.Duration
 	triesSoFar uint
+	aborted    bool
+
+	// pendingRetryAfter is requested during an attempt and becomes retryAfter once the attempt is over
+	pendingRetryAfter time.Duration
+	// retryAfter is the least amount of time to wait before the next attempt
+	retryAfter time.Duration
+
+	// nextWait is the wait planned for the next Yield, if planned is true
+	nextWait time.Duration
+	planned  bool
+	// yielded is true once the planned wait is over
+	yielded bool
+
+	// startedAt is when the first attempt was made, if started is true
+	startedAt time.Time
+	started   bool
+
+	// previousErr is the error of the latest attempt to finish
+	previousErr error
 }
 
 // ShouldTry will execute unless all of our retries allotted have failed
 func (c *maxExponentialService) ShouldTry() bool {
-	return c.triesSoFar < c.config.Times
+	return !c.aborted && !c.exhausted()
+}
+
+// exhausted is true once every attempt allotted has been made, or the next wait would end past the MaxElapsedTime
+func (c *maxExponentialService) exhausted() bool {
+	return (c.config.Times != Unlimited && c.triesSoFar >= c.config.Times) || c.outOfTime()
+}
+
+// outOfTime is true if waiting for the next attempt would end past the MaxElapsedTime
+func (c *maxExponentialService) outOfTime() bool {
+	if c.config.MaxElapsedTime == 0 || !c.started {
+		return false
+	}
+	var wait time.Duration
+	if !c.yielded {
+		wait = c.NextWait()
+	}
+	elapsed := c.Clock().Now().Sub(c.startedAt)
+	return saturatingAdd(elapsed, wait) > c.config.MaxElapsedTime
+}
+
+// AllowAttempt never refuses, but notes when the first attempt was made for MaxElapsedTime
+func (c *maxExponentialService) AllowAttempt() error {
+	c.start()
+	return nil
+}
+
+// AttemptDone notes the error for PreviousError, the attempt is counted by NotifyRetry
+func (c *maxExponentialService) AttemptDone(err error) {
+	c.previousErr = err
+}
+
+// start notes when the first attempt was made
+func (c *maxExponentialService) start() {
+	if !c.started {
+		c.startedAt = c.Clock().Now()
+		c.started = true
+	}
+}
+
+// Reason explains why ShouldTry is false
+func (c *maxExponentialService) Reason() error {
+	if c.aborted {
+		return ErrAborted
+	}
+	if c.exhausted() {
+		return ErrExhausted
+	}
+	return nil
 }
 
 // Wait will cause go to sleep for the WaitFor
 func (c *maxExponentialService) Yield() {
-	time.Sleep(c.waitDuration())
+	c.Clock().Sleep(c.NextWait())
+	c.yielded = true
+}
+
+// YieldContext will cause go to sleep for the WaitFor, or until ctx is done
+func (c *maxExponentialService) YieldContext(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-c.Clock().After(c.NextWait()):
+	}
+	c.yielded = true
+}
+
+// markYielded notes that the wait is over, even though another Service did the waiting
+func (c *maxExponentialService) markYielded() {
+	c.yielded = true
+}
+
+// Clock returns the configured clock, or the system clock
+func (c *maxExponentialService) Clock() Clock {
+	return clockOrDefault(c.config.Clock)
+}
+
+// NextWait returns how long the next Yield will wait. Jitter is only applied once per wait.
+func (c *maxExponentialService) NextWait() time.Duration {
+	if !c.planned {
+		c.nextWait = c.waitDuration()
+		c.planned = true
+	}
+	return c.nextWait
 }
 
 func (c maxExponentialService) waitDuration() time.Duration {
-	// implement the equation: a*B^x + y, and constrain to the bounds, if necessary
 	var waitFor time.Duration
-	switch c.config.Base {
-	case 0:
+	if c.equation != nil {
+		waitFor = c.equation(c.triesSoFar)
+	} else {
+		waitFor = c.exponentialWait()
+	}
+
+	if c.config.MaxAttemptWaitTime != 0 && waitFor > c.config.MaxAttemptWaitTime {
+		// Constrain the wait time
+		waitFor = c.config.MaxAttemptWaitTime
+	}
+
+	waitFor = c.config.Jitter.apply(waitFor)
+
+	if c.retryAfter > waitFor {
+		// asked to wait longer than planned, but still within the bounds
+		waitFor = c.retryAfter
+		if c.config.MaxAttemptWaitTime != 0 && waitFor > c.config.MaxAttemptWaitTime {
+			waitFor = c.config.MaxAttemptWaitTime
+		}
+	}
+	return waitFor
+}
+
+func (c maxExponentialService) exponentialWait() time.Duration {
+	// implement the equation: a*B^x + y, stopping at the largest duration rather than overflowing
+	var exponent uint
+	if c.triesSoFar > 0 {
+		exponent = c.triesSoFar - 1
+	}
+	var waitFor time.Duration
+	switch {
+	case c.config.Multiplier != 0:
+		// fractional growth, the scaling is applied as a float so that it saturates rather than wraps
+		waitFor = saturatingFloat(float64(c.config.Scaling) * math.Pow(c.config.Multiplier, float64(exponent)))
+		return saturatingAdd(waitFor, c.config.YOffset)
+	case c.config.Base == 0:
 		// do nothing, no exponent, constant function
-	case 1:
+	case c.config.Base == 1:
 		// constant with scaling
 		waitFor = 1
-	case 2:
+	case c.config.Base == 2:
 		// cheat for base 2
-		waitFor = 1 << (c.triesSoFar - 1)
+		if exponent >= 63 {
+			waitFor = math.MaxInt64
+		} else {
+			waitFor = 1 << exponent
+		}
 	default:
 		// Only perform power if necessary for other bases
-		waitFor = time.Duration(math.Pow(float64(c.config.Base), math.Max(0.0, float64(c.triesSoFar-1))))
+		waitFor = saturatingFloat(math.Pow(float64(c.config.Base), float64(exponent)))
 	}
 
 	// scaling factor
-	waitFor = waitFor * c.config.Scaling
+	waitFor = saturatingMul(waitFor, c.config.Scaling)
 
 	// add the YOffset
-	waitFor = waitFor + c.config.YOffset
+	return saturatingAdd(waitFor, c.config.YOffset)
+}
 
-	if c.config.MaxAttemptWaitTime != 0 && waitFor > c.config.MaxAttemptWaitTime {
-		// Constrain the wait time
-		waitFor = c.config.MaxAttemptWaitTime
+// AttemptTimeout returns how long the next attempt may run, or 0 for no limit
+func (c *maxExponentialService) AttemptTimeout() time.Duration {
+	return attemptTimeout(c.config.AttemptTimeout, c.config.AttemptTimeoutGrowth, c.triesSoFar)
+}
+
+// Attempt returns the number of the attempt in progress, starting at 1
+func (c *maxExponentialService) Attempt() uint {
+	return c.triesSoFar + 1
+}
+
+// Elapsed returns how long ago the first attempt started
+func (c *maxExponentialService) Elapsed() time.Duration {
+	if !c.started {
+		return 0
 	}
-	return waitFor
+	return c.Clock().Now().Sub(c.startedAt)
+}
+
+// PreviousError returns the error of the previous attempt, or nil during the first
+func (c *maxExponentialService) PreviousError() error {
+	return c.previousErr
+}
+
+// Remaining returns how many more attempts may follow this one, or Unlimited
+func (c *maxExponentialService) Remaining() uint {
+	if c.config.Times == Unlimited {
+		return Unlimited
+	}
+	if c.triesSoFar+1 >= c.config.Times {
+		return 0
+	}
+	return c.config.Times - c.triesSoFar - 1
 }
 
 // Returns the svc for the service so that the developer can svc it
@@ -122,15 +342,31 @@ func (c *maxExponentialService) Controller() ServiceController {
 
 // Wait will cause go to sleep for the WaitFor
 func (c *maxExponentialService) Abort() {
-	c.triesSoFar = c.config.Times
+	c.aborted = true
+}
+
+// RetryAfter sets the least amount of time to wait after the current attempt
+func (c *maxExponentialService) RetryAfter(d time.Duration) {
+	if d > c.pendingRetryAfter {
+		c.pendingRetryAfter = d
+	}
 }
 
 // Wait will cause go to sleep for the WaitFor
 func (c *maxExponentialService) NotifyRetry() {
+	// in case the attempt was made without asking AllowAttempt first
+	c.start()
 	c.triesSoFar++
+	c.retryAfter = c.pendingRetryAfter
+	c.pendingRetryAfter = 0
+	c.planned = false
+	c.yielded = false
 }
 
-// NewErrorList creates the default error list
+// NewErrorList creates the configured error list, or the default one
 func (c *maxExponentialService) NewErrorList() ErrorAppender {
+	if c.config.ErrorList != nil {
+		return c.config.ErrorList.New()
+	}
 	return newErrorList()
 }
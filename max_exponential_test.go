@@ -15,6 +15,9 @@
 package retry
 
 import (
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"math"
 	"testing"
 	"time"
 )
@@ -77,6 +80,22 @@ func TestMaxExponential_New(t *testing.T) {
 			},
 			expected: []time.Duration{20 * time.Second, 30 * time.Second, 50 * time.Second, 50 * time.Second, 50 * time.Second},
 		},
+		"multiplier: 1sec*1.5^x": {
+			cfg: Exponential{
+				Multiplier: 1.5,
+				Scaling:    1 * time.Second,
+			},
+			expected: []time.Duration{1 * time.Second, 1500 * time.Millisecond, 2250 * time.Millisecond, 3375 * time.Millisecond},
+		},
+		"multiplier: 1sec*1.6^x + 1sec (limit 3sec)": {
+			cfg: Exponential{
+				Multiplier:         1.6,
+				Scaling:            1 * time.Second,
+				YOffset:            1 * time.Second,
+				MaxAttemptWaitTime: 3 * time.Second,
+			},
+			expected: []time.Duration{2 * time.Second, 2600 * time.Millisecond, 3 * time.Second, 3 * time.Second},
+		},
 	}
 
 	for caseName, c := range cases {
@@ -109,3 +128,114 @@ func TestExpBase2_New(t *testing.T) {
 		t.Error("Expected base to be 2, but got ", base2.config.Base)
 	}
 }
+
+func TestMaxExponential_Saturates(t *testing.T) {
+	cases := map[string]Exponential{
+		"base 2": {
+			Base:    2,
+			Scaling: time.Second,
+			YOffset: time.Second,
+		},
+		"base 10": {
+			Base:    10,
+			Scaling: time.Second,
+		},
+		"multiplier": {
+			Multiplier: 1.5,
+			Scaling:    time.Second,
+			YOffset:    time.Second,
+		},
+	}
+
+	for caseName, cfg := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			svc := cfg.New().(*maxExponentialService)
+			var last time.Duration
+			for i := 0; i < 200; i++ {
+				svc.NotifyRetry()
+				wait := svc.waitDuration()
+				if wait < last {
+					t.Fatalf(`wait %d: expected the wait to never shrink, but went from %v to %v`, i+1, last, wait)
+				}
+				last = wait
+			}
+			if last != math.MaxInt64 {
+				t.Errorf(`expected the wait to stop at the largest duration, but got %v`, last)
+			}
+		})
+	}
+}
+
+func TestMaxExponential_SaturatesAtMaxAttemptWaitTime(t *testing.T) {
+	svc := Exponential{
+		Multiplier:         1.6,
+		Scaling:            time.Second,
+		MaxAttemptWaitTime: time.Minute,
+	}.New().(*maxExponentialService)
+	for i := 0; i < 500; i++ {
+		svc.NotifyRetry()
+	}
+	if actual := svc.waitDuration(); actual != time.Minute {
+		t.Errorf(`expected duration: %v but got %v`, time.Minute, actual)
+	}
+}
+
+// TestMaxElapsedTime ensures that retrying stops before a wait that would end past the MaxElapsedTime
+func TestMaxElapsedTime(t *testing.T) {
+	cases := map[string]struct {
+		factory     func(clock Clock) Factory
+		attemptTime time.Duration
+		attempts    int
+		waits       []time.Duration
+	}{
+		"max attempts": {
+			factory: func(clock Clock) Factory {
+				return MaxAttempts{Times: Unlimited, WaitFor: 10 * time.Second, MaxElapsedTime: 35 * time.Second, Clock: clock}
+			},
+			attempts: 4,
+			waits:    []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second},
+		},
+		"exp base 2": {
+			factory: func(clock Clock) Factory {
+				return ExpBase2{Times: 10, Scaling: time.Second, MaxElapsedTime: 10 * time.Second, Clock: clock}
+			},
+			attempts: 4,
+			waits:    []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		"exponential with slow attempts": {
+			factory: func(clock Clock) Factory {
+				return Exponential{Times: 10, Base: 2, Scaling: time.Second, MaxElapsedTime: 10 * time.Second, Clock: clock}
+			},
+			attemptTime: 2 * time.Second,
+			attempts:    3,
+			waits:       []time.Duration{1 * time.Second, 2 * time.Second},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			clock := retrytest.NewAutoClock(time.Now())
+			attempts := 0
+			errList := How(c.factory(clock).New()).This(func(controller ServiceController) error {
+				attempts++
+				clock.Advance(c.attemptTime)
+				return errors.New("boom")
+			})
+			if attempts != c.attempts {
+				t.Errorf(`expected %d attempts, but got %d`, c.attempts, attempts)
+			}
+			if !errors.Is(errList.Reason(), ErrExhausted) {
+				t.Errorf(`expected reason: %v but got %v`, ErrExhausted, errList.Reason())
+			}
+			actual := clock.Waits()
+			if len(actual) != len(c.waits) {
+				t.Fatalf(`expected waits %v, but got %v`, c.waits, actual)
+			}
+			for i := range c.waits {
+				if c.waits[i] != actual[i] {
+					t.Errorf(`wait %d: expected duration: %v but got %v`, i+1, c.waits[i], actual[i])
+				}
+			}
+		})
+	}
+}
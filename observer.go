@@ -0,0 +1,90 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import "time"
+
+// Observer is told what happens in the retry loop, e.g. to log or measure it. Pass any number of them to How.
+// Observers are called synchronously from the retry loop, so they should be quick.
+type Observer interface {
+	// OnAttempt is called just before each attempt
+	OnAttempt(e Event)
+
+	// OnRetry is called after an attempt failed and before waiting for the next one
+	OnRetry(e Event)
+
+	// OnGiveUp is called once when retrying stops without success
+	OnGiveUp(e Event)
+
+	// OnSuccess is called once when an attempt succeeds
+	OnSuccess(e Event)
+}
+
+// Event describes what just happened in the retry loop. Fields that do not apply to a callback are left empty.
+type Event struct {
+	// Attempt is the number of the attempt, starting at 1. It is 0 if retrying stopped before the first attempt.
+	Attempt uint
+
+	// Err is the error returned by the attempt, for OnRetry and OnGiveUp
+	Err error
+
+	// Wait is how long the Service plans to wait before the next attempt, for OnRetry. It is 0 if the Service does not
+	// implement WaitPlanner.
+	Wait time.Duration
+
+	// Waited is how long was actually spent in Yield right before this event, for OnAttempt and OnGiveUp
+	Waited time.Duration
+
+	// Elapsed is the time since the first attempt started
+	Elapsed time.Duration
+
+	// Reason is why retrying stopped, for OnGiveUp
+	Reason error
+
+	// Errors holds every error recorded, for OnGiveUp
+	Errors Errorer
+}
+
+// ObserverFuncs is an Observer that calls whichever functions are set and ignores the rest
+type ObserverFuncs struct {
+	Attempt func(e Event)
+	Retry   func(e Event)
+	GiveUp  func(e Event)
+	Success func(e Event)
+}
+
+func (o ObserverFuncs) OnAttempt(e Event) {
+	if o.Attempt != nil {
+		o.Attempt(e)
+	}
+}
+
+func (o ObserverFuncs) OnRetry(e Event) {
+	if o.Retry != nil {
+		o.Retry(e)
+	}
+}
+
+func (o ObserverFuncs) OnGiveUp(e Event) {
+	if o.GiveUp != nil {
+		o.GiveUp(e)
+	}
+}
+
+func (o ObserverFuncs) OnSuccess(e Event) {
+	if o.Success != nil {
+		o.Success(e)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"testing"
+	"time"
+)
+
+// recordedEvent is a callback name and the Event it was given
+type recordedEvent struct {
+	callback string
+	event    Event
+}
+
+// recordingObserver keeps every event it is told about
+type recordingObserver struct {
+	events []recordedEvent
+}
+
+func (o *recordingObserver) OnAttempt(e Event) {
+	o.events = append(o.events, recordedEvent{callback: "attempt", event: e})
+}
+
+func (o *recordingObserver) OnRetry(e Event) {
+	o.events = append(o.events, recordedEvent{callback: "retry", event: e})
+}
+
+func (o *recordingObserver) OnGiveUp(e Event) {
+	o.events = append(o.events, recordedEvent{callback: "give up", event: e})
+}
+
+func (o *recordingObserver) OnSuccess(e Event) {
+	o.events = append(o.events, recordedEvent{callback: "success", event: e})
+}
+
+func TestRetry_Observer(t *testing.T) {
+	boom := errors.New("boom")
+	cases := map[string]struct {
+		failures int
+		expected []recordedEvent
+	}{
+		"succeed on the third attempt": {
+			failures: 2,
+			expected: []recordedEvent{
+				{callback: "attempt", event: Event{Attempt: 1}},
+				{callback: "retry", event: Event{Attempt: 1, Err: boom, Wait: 1 * time.Second}},
+				{callback: "attempt", event: Event{Attempt: 2, Waited: 1 * time.Second, Elapsed: 1 * time.Second}},
+				{callback: "retry", event: Event{Attempt: 2, Err: boom, Wait: 2 * time.Second, Elapsed: 1 * time.Second}},
+				{callback: "attempt", event: Event{Attempt: 3, Waited: 2 * time.Second, Elapsed: 3 * time.Second}},
+				{callback: "success", event: Event{Attempt: 3, Elapsed: 3 * time.Second}},
+			},
+		},
+		"give up": {
+			failures: 3,
+			expected: []recordedEvent{
+				{callback: "attempt", event: Event{Attempt: 1}},
+				{callback: "retry", event: Event{Attempt: 1, Err: boom, Wait: 1 * time.Second}},
+				{callback: "attempt", event: Event{Attempt: 2, Waited: 1 * time.Second, Elapsed: 1 * time.Second}},
+				{callback: "retry", event: Event{Attempt: 2, Err: boom, Wait: 2 * time.Second, Elapsed: 1 * time.Second}},
+				{callback: "attempt", event: Event{Attempt: 3, Waited: 2 * time.Second, Elapsed: 3 * time.Second}},
+				{callback: "give up", event: Event{Attempt: 3, Err: boom, Elapsed: 3 * time.Second, Reason: ErrExhausted}},
+			},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			first, second := &recordingObserver{}, &recordingObserver{}
+			attempt := 0
+			errList := How(ExpBase2{
+				Times:   3,
+				Scaling: 1 * time.Second,
+				Clock:   retrytest.NewAutoClock(time.Now()),
+			}.New(), first, second).This(func(controller ServiceController) error {
+				attempt++
+				if attempt <= c.failures {
+					return boom
+				}
+				return nil
+			})
+
+			for _, observer := range []*recordingObserver{first, second} {
+				if len(observer.events) != len(c.expected) {
+					t.Fatalf(`expected %d events, got: %d`, len(c.expected), len(observer.events))
+				}
+				for i, expected := range c.expected {
+					actual := observer.events[i]
+					if actual.event.Errors != nil && actual.event.Errors != errList {
+						t.Error("expected the give up event to have the returned errors")
+					}
+					actual.event.Errors = nil
+					if actual != expected {
+						t.Errorf(`expected event: %+v but got: %+v`, expected, actual)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestObserverFuncs(t *testing.T) {
+	var called []string
+	o := ObserverFuncs{
+		Retry: func(e Event) {
+			called = append(called, "retry")
+		},
+		GiveUp: func(e Event) {
+			called = append(called, "give up")
+		},
+	}
+	_ = How(MaxAttempts{Times: 2}.New(), o).This(func(controller ServiceController) error {
+		return errors.New("boom")
+	})
+	if len(called) != 2 || called[0] != "retry" || called[1] != "give up" {
+		t.Errorf(`expected only the set functions to be called, got: %v`, called)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// These helpers use a Service's optional interfaces when it has them, and fall back to sensible defaults when it
+// does not. Services that wrap other Services use them to pass the optional interfaces through.
+
+// clocked is implemented by Services that tell the time with a Clock. The retry loop uses it to measure Events.
+type clocked interface {
+	Clock() Clock
+}
+
+// stopper is implemented by Services that stop retrying once a channel is closed, e.g. when their context is done.
+// Services that combine others watch it to stop waiting as soon as any of them would stop.
+type stopper interface {
+	done() <-chan struct{}
+}
+
+// doneOf returns the channel that is closed when the service stops retrying, or nil if it has none
+func doneOf(svc Service) <-chan struct{} {
+	if s, ok := svc.(stopper); ok {
+		return s.done()
+	}
+	return nil
+}
+
+// yieldMarker is implemented by Services that track whether their wait is over. Services that combine others use it
+// to tell the children that did not yield themselves that the wait has been spent.
+type yieldMarker interface {
+	markYielded()
+}
+
+// markYielded tells the service that the wait for the next attempt is over, if it wants to know
+func markYielded(svc Service) {
+	if marker, ok := svc.(yieldMarker); ok {
+		marker.markYielded()
+	}
+}
+
+// retryAfter asks the controller to wait at least d before the next attempt, if it can
+func retryAfter(controller ServiceController, d time.Duration) {
+	if setter, ok := controller.(RetryAfterSetter); ok {
+		setter.RetryAfter(d)
+	}
+}
+
+// clockOf returns the service's clock, or the system clock
+func clockOf(svc Service) Clock {
+	if c, ok := svc.(clocked); ok {
+		return clockOrDefault(c.Clock())
+	}
+	return realClock{}
+}
+
+// reasonOf asks the service why it stopped, returns nil if it cannot say
+func reasonOf(svc Service) error {
+	if reasoner, ok := svc.(Reasoner); ok {
+		return reasoner.Reason()
+	}
+	return nil
+}
+
+// nextWaitOf asks the service how long it will wait, returns 0 if it cannot say
+func nextWaitOf(svc Service) time.Duration {
+	if planner, ok := svc.(WaitPlanner); ok {
+		return planner.NextWait()
+	}
+	return 0
+}
+
+// attemptTimeoutOf asks the service how long the next attempt may run, returns 0 if there is no limit
+func attemptTimeoutOf(svc Service) time.Duration {
+	if timeouter, ok := svc.(AttemptTimeouter); ok {
+		return timeouter.AttemptTimeout()
+	}
+	return 0
+}
+
+// remainingOf asks the service's controller how many attempts it has left, Unlimited if it cannot say
+func remainingOf(svc Service) uint {
+	if info, ok := svc.Controller().(AttemptInfo); ok {
+		return info.Remaining()
+	}
+	return Unlimited
+}
+
+// yieldContext waits for the service, but returns early once ctx is done
+func yieldContext(svc Service, ctx context.Context) {
+	if yielder, ok := svc.(ContextYielder); ok {
+		yielder.YieldContext(ctx)
+		return
+	}
+	if ctx.Done() == nil {
+		// ctx can never be done, no need to watch it
+		svc.Yield()
+		return
+	}
+	yielded := make(chan struct{})
+	go func() {
+		svc.Yield()
+		close(yielded)
+	}()
+	select {
+	case <-yielded:
+	case <-ctx.Done():
+	}
+}
+
+// wrapper passes every optional interface through to the Service it wraps. Embed it in a Service that decorates
+// another, then override only what the decorator changes.
+type wrapper struct {
+	Service
+}
+
+func (w wrapper) YieldContext(ctx context.Context) {
+	yieldContext(w.Service, ctx)
+}
+
+func (w wrapper) NextWait() time.Duration {
+	return nextWaitOf(w.Service)
+}
+
+func (w wrapper) AttemptTimeout() time.Duration {
+	return attemptTimeoutOf(w.Service)
+}
+
+func (w wrapper) Clock() Clock {
+	return clockOf(w.Service)
+}
+
+func (w wrapper) Reason() error {
+	return reasonOf(w.Service)
+}
+
+func (w wrapper) done() <-chan struct{} {
+	return doneOf(w.Service)
+}
+
+func (w wrapper) markYielded() {
+	markYielded(w.Service)
+}
+
+func (w wrapper) AllowAttempt() error {
+	if gate, ok := w.Service.(AttemptGate); ok {
+		return gate.AllowAttempt()
+	}
+	return nil
+}
+
+func (w wrapper) AttemptDone(err error) {
+	if gate, ok := w.Service.(AttemptGate); ok {
+		gate.AttemptDone(err)
+	}
+}
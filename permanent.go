@@ -0,0 +1,87 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import "errors"
+
+// PermanentError marks an error that retrying cannot fix. How(...).This stops as soon as an attempt returns one.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps err so that no more attempts are made after it is returned. Returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error so errors.Is and errors.As still find it
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent always returns true
+func (e *PermanentError) Permanent() bool {
+	return true
+}
+
+// RetryableError marks an error that is worth retrying, even if it wraps an error that was marked as permanent
+type RetryableError struct {
+	Err error
+}
+
+// Retryable wraps err so that it will be retried. Returns nil if err is nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryableError{Err: err}
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error so errors.Is and errors.As still find it
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent always returns false
+func (e *RetryableError) Permanent() bool {
+	return false
+}
+
+// permanenceMarker is implemented by errors that know whether they are worth retrying
+type permanenceMarker interface {
+	Permanent() bool
+}
+
+// IsPermanent reports whether err should not be retried. The outermost error in the chain with a Permanent() bool
+// method decides, so Retryable(Permanent(err)) is retryable. Your own error types may implement that method, too.
+// Errors without any marker are retryable.
+func IsPermanent(err error) bool {
+	var marker permanenceMarker
+	if errors.As(err, &marker) {
+		return marker.Permanent()
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// customPermanent is a user-defined error that marks itself as permanent
+type customPermanent struct{}
+
+func (customPermanent) Error() string   { return "custom" }
+func (customPermanent) Permanent() bool { return true }
+
+func TestIsPermanent(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"nil": {
+			err:      nil,
+			expected: false,
+		},
+		"unmarked": {
+			err:      io.EOF,
+			expected: false,
+		},
+		"permanent": {
+			err:      Permanent(io.EOF),
+			expected: true,
+		},
+		"retryable": {
+			err:      Retryable(io.EOF),
+			expected: false,
+		},
+		"retryable wrapping permanent": {
+			err:      Retryable(Permanent(io.EOF)),
+			expected: false,
+		},
+		"permanent wrapping retryable": {
+			err:      Permanent(Retryable(io.EOF)),
+			expected: true,
+		},
+		"custom": {
+			err:      customPermanent{},
+			expected: true,
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			if IsPermanent(c.err) != c.expected {
+				t.Errorf(`expected IsPermanent to be %v, but was not`, c.expected)
+			}
+		})
+	}
+}
+
+func TestPermanent_Nil(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Error("expected a nil error to stay nil")
+	}
+	if Retryable(nil) != nil {
+		t.Error("expected a nil error to stay nil")
+	}
+}
+
+// TestRetry_Permanent ensures that a permanent error stops the retries at once without a Yield
+func TestRetry_Permanent(t *testing.T) {
+	attempts := 0
+	startTime := time.Now()
+	errList := How(MaxAttempts{Times: 3, WaitFor: 10 * time.Second}.New()).This(func(controller ServiceController) error {
+		attempts++
+		return Permanent(io.EOF)
+	})
+	if time.Since(startTime) >= 10*time.Second {
+		t.Error("retry waited after a permanent error and should not have")
+	}
+	if attempts != 1 {
+		t.Errorf(`expected 1 attempt, got: %d`, attempts)
+	}
+	if errList == nil {
+		t.Fatal("expected an error list")
+	}
+	if len(errList.Errors()) != 1 {
+		t.Errorf(`expected 1 error, got: %d`, len(errList.Errors()))
+	}
+	if !errors.Is(errList.Last(), io.EOF) {
+		t.Error("expected the permanent error to unwrap to the original error")
+	}
+}
+
+// TestRetry_Retryable ensures that a retryable error is retried
+func TestRetry_Retryable(t *testing.T) {
+	attempts := 0
+	_ = How(MaxAttempts{Times: 3}.New()).This(func(controller ServiceController) error {
+		attempts++
+		return Retryable(Permanent(io.EOF))
+	})
+	if attempts != 3 {
+		t.Errorf(`expected 3 attempts, got: %d`, attempts)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPolynomial_New(t *testing.T) {
+	cases := map[string]struct {
+		cfg      Polynomial
+		expected []time.Duration
+	}{
+		"1sec*x^2": {
+			cfg: Polynomial{
+				Scaling:  1 * time.Second,
+				Exponent: 2,
+			},
+			expected: []time.Duration{1 * time.Second, 4 * time.Second, 9 * time.Second, 16 * time.Second, 25 * time.Second},
+		},
+		"1sec*x^1 + 500ms": {
+			cfg: Polynomial{
+				Scaling:  1 * time.Second,
+				Exponent: 1,
+				YOffset:  500 * time.Millisecond,
+			},
+			expected: []time.Duration{1500 * time.Millisecond, 2500 * time.Millisecond, 3500 * time.Millisecond},
+		},
+		"10ms*x^3 (limit 1sec)": {
+			cfg: Polynomial{
+				Scaling:            10 * time.Millisecond,
+				Exponent:           3,
+				MaxAttemptWaitTime: 1 * time.Second,
+			},
+			expected: []time.Duration{10 * time.Millisecond, 80 * time.Millisecond, 270 * time.Millisecond, 640 * time.Millisecond, 1 * time.Second},
+		},
+		"1sec*x^2 (proportional jitter)": {
+			cfg: Polynomial{
+				Scaling:  1 * time.Second,
+				Exponent: 2,
+				Jitter:   Jitter{Mode: JitterProportional, Factor: 0.5, Source: fixedRandom(1)},
+			},
+			expected: []time.Duration{1500 * time.Millisecond, 6 * time.Second, 13500 * time.Millisecond},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			svc := c.cfg.New().(*maxExponentialService)
+			for i, expected := range c.expected {
+				svc.NotifyRetry()
+				if actual := svc.waitDuration(); actual != expected {
+					t.Errorf(`wait %d: expected duration: %v but got %v`, i+1, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestPolynomial_Saturates(t *testing.T) {
+	svc := Polynomial{Scaling: time.Hour, Exponent: 20}.New().(*maxExponentialService)
+	for i := 0; i < 100; i++ {
+		svc.NotifyRetry()
+	}
+	if actual := svc.waitDuration(); actual != math.MaxInt64 {
+		t.Errorf(`expected the wait to stop at the largest duration, but got %v`, actual)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrExhausted is the Reason retrying stopped when the Service ran out of attempts
+	ErrExhausted = errors.New("retries exhausted")
+
+	// ErrAborted is the Reason retrying stopped when ServiceController.Abort was called, or an attempt returned a
+	// permanent error
+	ErrAborted = errors.New("retries aborted")
+
+	// ErrContextDone is the Reason retrying stopped when a context ended. The Reason also wraps context.Cause, so
+	// errors.Is can tell a cancellation apart from a deadline or a custom cause.
+	ErrContextDone = errors.New("context done")
+)
+
+// contextDone creates the Reason for a context that has ended
+func contextDone(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrContextDone, context.Cause(ctx))
+}
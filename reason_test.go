@@ -0,0 +1,126 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"testing"
+	"time"
+)
+
+func TestRetry_Reason(t *testing.T) {
+	boom := errors.New("boom")
+	cases := map[string]struct {
+		test     func(controller ServiceController) error
+		expected error
+	}{
+		"exhausted": {
+			test: func(controller ServiceController) error {
+				return boom
+			},
+			expected: ErrExhausted,
+		},
+		"aborted": {
+			test: func(controller ServiceController) error {
+				controller.Abort()
+				return boom
+			},
+			expected: ErrAborted,
+		},
+		"permanent": {
+			test: func(controller ServiceController) error {
+				return Permanent(boom)
+			},
+			expected: ErrAborted,
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			errList := How(MaxAttempts{Times: 3}.New()).This(c.test)
+			if errList == nil {
+				t.Fatal("expected an error list")
+			}
+			if errList.Reason() != c.expected {
+				t.Errorf(`expected reason: "%v" but got: "%v"`, c.expected, errList.Reason())
+			}
+			if !errors.Is(errList, c.expected) {
+				t.Error("expected errors.Is to match the reason")
+			}
+		})
+	}
+}
+
+// TestRetry_Reason_ServiceContext ensures that the service's context ending during Yield is reported, with its cause,
+// and that no more attempts are made
+func TestRetry_Reason_ServiceContext(t *testing.T) {
+	dependencyDown := errors.New("dependency down")
+	clock := retrytest.NewClock(time.Now())
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	attempts := 0
+	done := make(chan Errorer)
+	go func() {
+		done <- How(ExpBase2{
+			Times:   5,
+			Scaling: 1 * time.Minute,
+			Clock:   clock,
+		}.NewWithContext(ctx)).This(func(controller ServiceController) error {
+			attempts++
+			return errors.New("boom")
+		})
+	}()
+	clock.BlockUntil(1)
+	cancel(dependencyDown)
+	errList := <-done
+
+	if attempts != 1 {
+		t.Errorf(`expected 1 attempt, got: %d`, attempts)
+	}
+	if !errors.Is(errList.Reason(), ErrContextDone) {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrContextDone, errList.Reason())
+	}
+	if !errors.Is(errList.Reason(), dependencyDown) {
+		t.Errorf(`expected the reason to carry the cause, but got: "%v"`, errList.Reason())
+	}
+}
+
+// TestRetry_Reason_ThisContext ensures that the caller's context ending is reported
+func TestRetry_Reason_ThisContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errList := How(MaxAttempts{Times: 3}.New()).ThisContext(ctx, func(ctx context.Context, controller ServiceController) error {
+		cancel()
+		return errors.New("boom")
+	})
+	if !errors.Is(errList.Reason(), ErrContextDone) {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrContextDone, errList.Reason())
+	}
+	if !errors.Is(errList.Reason(), context.Canceled) {
+		t.Errorf(`expected the reason to carry the cause, but got: "%v"`, errList.Reason())
+	}
+}
+
+// TestRetry_Reason_NotReasoner ensures that services that cannot explain themselves are assumed to be exhausted
+func TestRetry_Reason_NotReasoner(t *testing.T) {
+	errList := How(&plainService{Service: MaxAttempts{Times: 1}.New()}).This(func(controller ServiceController) error {
+		controller.Abort()
+		return errors.New("boom")
+	})
+	if errList.Reason() != ErrExhausted {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrExhausted, errList.Reason())
+	}
+}
@@ -14,44 +14,158 @@
 
 package retry
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
 type basic struct {
-	svc Service
+	svc       Service
+	observers []Observer
 }
 
-// How creates a new retry service
-func How(svc Service) Retrier {
+// How creates a new retry service. Each of the observers, if any, is told about every attempt.
+func How(svc Service, observers ...Observer) Retrier {
 	return &basic{
-		svc: svc,
+		svc:       svc,
+		observers: observers,
 	}
 }
 
 // This invokes the developer's method to retry
 func (b *basic) This(test func(controller ServiceController) error) Errorer {
+	return b.ThisContext(context.Background(), func(_ context.Context, controller ServiceController) error {
+		return test(controller)
+	})
+}
+
+// ThisContext invokes the developer's method to retry, stopping early if ctx is done
+func (b *basic) ThisContext(ctx context.Context, test func(ctx context.Context, controller ServiceController) error) Errorer {
 	var errorList ErrorAppender
+	clock := clockOf(b.svc)
+	startedAt := clock.Now()
+	var attempt uint
+	var waited time.Duration
+	gate, gated := b.svc.(AttemptGate)
 	// Retry until we should not
-	for true {
-		// Perform the action under test, this is the thing the developer would like to retry
-		err := test(b.svc.Controller())
-		// Notify our service that the try/retry has occurred
-		b.svc.NotifyRetry()
-		if err != nil {
+	for {
+		if ctx.Err() != nil {
+			// the caller gave up, do not make any more attempts
 			if errorList == nil {
-				// factory a new error list, if not yet created (lazy-create)
 				errorList = b.svc.NewErrorList()
+				errorList.Append(ctx.Err())
 			}
-			// Got an error, record it
-			errorList.Append(err)
-			// Wait, but only if we should try again
-			if b.svc.ShouldTry() {
-				b.svc.Yield()
-			} else {
-				return errorList
+			return b.giveUp(errorList, contextDone(ctx), Event{Attempt: attempt, Waited: waited, Elapsed: clock.Now().Sub(startedAt)})
+		}
+		if errorList != nil && !b.svc.ShouldTry() {
+			// the service gave up while yielding, e.g. its own context ended
+			return b.giveUp(errorList, b.reason(), Event{Attempt: attempt, Waited: waited, Elapsed: clock.Now().Sub(startedAt)})
+		}
+		if gated {
+			if err := gate.AllowAttempt(); err != nil {
+				// not allowed to even try
+				if errorList == nil {
+					errorList = b.svc.NewErrorList()
+				}
+				errorList.Append(err)
+				return b.giveUp(errorList, err, Event{Attempt: attempt, Waited: waited, Elapsed: clock.Now().Sub(startedAt)})
 			}
-		} else {
+		}
+		attempt++
+		b.each(Observer.OnAttempt, Event{Attempt: attempt, Waited: waited, Elapsed: clock.Now().Sub(startedAt)})
+		// Perform the action under test, this is the thing the developer would like to retry
+		attemptedAt := clock.Now()
+		err := b.attempt(ctx, test)
+		attemptDuration := clock.Now().Sub(attemptedAt)
+		if gated {
+			gate.AttemptDone(attemptOutcome(ctx, err))
+		}
+		var retryAfterer RetryAfterer
+		if errors.As(err, &retryAfterer) {
+			// the error knows how long to wait, e.g. a server said when to come back
+			retryAfter(b.svc.Controller(), retryAfterer.RetryAfter())
+		}
+		// Notify our service that the try/retry has occurred
+		b.svc.NotifyRetry()
+		if err == nil {
 			// success, no need to retry
+			b.each(Observer.OnSuccess, Event{Attempt: attempt, Elapsed: clock.Now().Sub(startedAt)})
 			return nil
 		}
+		if errorList == nil {
+			// factory a new error list, if not yet created (lazy-create)
+			errorList = b.svc.NewErrorList()
+		}
+		// Got an error, record it
+		errorList.Append(err)
+		recorder, recording := errorList.(AttemptRecorder)
+		if recording {
+			recorder.RecordAttempt(Attempt{Number: attempt, Start: attemptedAt, Duration: attemptDuration, Err: err})
+		}
+		if IsPermanent(err) {
+			return b.giveUp(errorList, ErrAborted, Event{Attempt: attempt, Elapsed: clock.Now().Sub(startedAt)})
+		}
+		// Wait, but only if we should try again
+		if !b.svc.ShouldTry() {
+			return b.giveUp(errorList, b.reason(), Event{Attempt: attempt, Elapsed: clock.Now().Sub(startedAt)})
+		}
+		b.each(Observer.OnRetry, Event{Attempt: attempt, Err: err, Wait: nextWaitOf(b.svc), Elapsed: clock.Now().Sub(startedAt)})
+		yieldedAt := clock.Now()
+		yieldContext(b.svc, ctx)
+		waited = clock.Now().Sub(yieldedAt)
+		if recording {
+			recorder.RecordWait(waited)
+		}
+	}
+}
+
+// reason asks the service why it stopped, assuming it ran out of attempts if it cannot say
+func (b *basic) reason() error {
+	if reason := reasonOf(b.svc); reason != nil {
+		return reason
+	}
+	return ErrExhausted
+}
+
+// giveUp records why retrying stopped, tells the observers and returns the errors
+func (b *basic) giveUp(errorList ErrorAppender, reason error, e Event) Errorer {
+	errorList.SetReason(reason)
+	e.Err = errorList.Last()
+	e.Reason = reason
+	e.Errors = errorList
+	b.each(Observer.OnGiveUp, e)
+	return errorList
+}
+
+// each calls the callback on every observer
+func (b *basic) each(callback func(o Observer, e Event), e Event) {
+	for _, o := range b.observers {
+		callback(o, e)
+	}
+}
+
+// attemptOutcome marks err as caused by the caller if it came from ctx ending
+func attemptOutcome(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return fmt.Errorf("%w: %w", ErrContextDone, err)
+	}
+	return err
+}
+
+// attempt performs a single try, limited by the service's attempt timeout, if it has one
+func (b *basic) attempt(ctx context.Context, test func(ctx context.Context, controller ServiceController) error) error {
+	timeout := attemptTimeoutOf(b.svc)
+	if timeout <= 0 {
+		return test(ctx, b.svc.Controller())
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := test(attemptCtx, b.svc.Controller())
+	if err != nil && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		// only this attempt ran out of time, not the caller
+		err = &AttemptTimeoutError{Timeout: timeout, Err: err}
 	}
-	// should be unreachable, but go is complaining that there is no return
-	return nil
+	return err
 }
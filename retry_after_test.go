@@ -0,0 +1,104 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"testing"
+	"time"
+)
+
+// throttledError asks to wait before the next attempt
+type throttledError time.Duration
+
+func (e throttledError) Error() string {
+	return "throttled"
+}
+
+func (e throttledError) RetryAfter() time.Duration {
+	return time.Duration(e)
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	cases := map[string]struct {
+		cfg      ExpBase2
+		test     func(attempt int, controller ServiceController) error
+		expected []time.Duration
+	}{
+		"error sets a floor": {
+			cfg: ExpBase2{Times: 4, Scaling: 1 * time.Second},
+			test: func(attempt int, controller ServiceController) error {
+				if attempt == 2 {
+					return throttledError(30 * time.Second)
+				}
+				return errors.New("boom")
+			},
+			expected: []time.Duration{1 * time.Second, 30 * time.Second, 4 * time.Second},
+		},
+		"wrapped error": {
+			cfg: ExpBase2{Times: 2, Scaling: 1 * time.Second},
+			test: func(attempt int, controller ServiceController) error {
+				return Retryable(throttledError(30 * time.Second))
+			},
+			expected: []time.Duration{30 * time.Second},
+		},
+		"shorter than planned": {
+			cfg: ExpBase2{Times: 2, Scaling: 1 * time.Minute},
+			test: func(attempt int, controller ServiceController) error {
+				return throttledError(30 * time.Second)
+			},
+			expected: []time.Duration{1 * time.Minute},
+		},
+		"controller": {
+			cfg: ExpBase2{Times: 3, Scaling: 1 * time.Second},
+			test: func(attempt int, controller ServiceController) error {
+				controller.(RetryAfterSetter).RetryAfter(20 * time.Second)
+				controller.(RetryAfterSetter).RetryAfter(10 * time.Second)
+				return errors.New("boom")
+			},
+			expected: []time.Duration{20 * time.Second, 20 * time.Second},
+		},
+		"limited by MaxAttemptWaitTime": {
+			cfg: ExpBase2{Times: 2, Scaling: 1 * time.Second, MaxAttemptWaitTime: 10 * time.Second},
+			test: func(attempt int, controller ServiceController) error {
+				return throttledError(1 * time.Hour)
+			},
+			expected: []time.Duration{10 * time.Second},
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			clock := retrytest.NewAutoClock(time.Now())
+			cfg := c.cfg
+			cfg.Clock = clock
+			attempt := 0
+			_ = How(cfg.New()).This(func(controller ServiceController) error {
+				attempt++
+				return c.test(attempt, controller)
+			})
+			actual := clock.Waits()
+			if len(actual) != len(c.expected) {
+				t.Fatalf(`expected %d waits, got: %d`, len(c.expected), len(actual))
+			}
+			for i := range c.expected {
+				if c.expected[i] != actual[i] {
+					t.Errorf(`expected duration: %v but got %v`, c.expected[i], actual[i])
+				}
+			}
+		})
+	}
+}
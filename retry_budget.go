@@ -0,0 +1,190 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted is the Reason retrying stopped when a RetryBudget had no retries left
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// retryBudgetBuckets is how many pieces the sliding window is split into
+const retryBudgetBuckets = 10
+
+// RetryBudget limits retries across every Service that shares it, so a struggling dependency is not swamped by
+// retries. Retries are allowed while they stay under Percent of the first attempts made over the sliding Window, plus
+// MinRetriesPerSecond so that quiet callers can still retry. Share one RetryBudget between calls and Wrap each call's
+// Service with it. Configure it before first use; it is then safe for concurrent use.
+type RetryBudget struct {
+	// Percent is the percentage of first attempts that may be retried, e.g. 20 allows 1 retry for every 5 calls
+	Percent float64
+
+	// MinRetriesPerSecond allows this many retries per second, regardless of Percent
+	MinRetriesPerSecond float64
+
+	// Window is how far back first attempts and retries are counted. Leave as 0 to use 10 seconds.
+	Window time.Duration
+
+	// Clock tells the time for the Window. Leave nil to use the system clock.
+	Clock Clock
+
+	mu      sync.Mutex
+	buckets [retryBudgetBuckets]retryBudgetBucket
+}
+
+// retryBudgetBucket counts the attempts made during one piece of the window
+type retryBudgetBucket struct {
+	index   int64
+	firsts  uint64
+	retries uint64
+}
+
+// Wrap returns a Service that follows svc, but only retries while the budget allows it
+func (b *RetryBudget) Wrap(svc Service) Service {
+	return &budgetService{
+		wrapper: wrapper{Service: svc},
+		budget:  b,
+	}
+}
+
+// first records a first attempt, which adds to the budget
+func (b *RetryBudget) first() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current().firsts++
+}
+
+// withdraw records a retry, if the budget allows it. Returns the index of the bucket it was taken from, or false if
+// the budget does not allow it.
+func (b *RetryBudget) withdraw() (index int64, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.allows() {
+		return 0, false
+	}
+	bucket := b.current()
+	bucket.retries++
+	return bucket.index, true
+}
+
+// refund gives back a retry that was withdrawn from the bucket at index, but never made. Nothing is given back if
+// that bucket has since left the window, as the retry no longer counts against the budget.
+func (b *RetryBudget) refund(index int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if index < b.index()-retryBudgetBuckets+1 {
+		return
+	}
+	if bucket := &b.buckets[index%retryBudgetBuckets]; bucket.index == index && bucket.retries > 0 {
+		bucket.retries--
+	}
+}
+
+// canRetry is true if the budget has a retry left
+func (b *RetryBudget) canRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.allows()
+}
+
+// allows is true if one more retry stays within the budget. Must be called with the lock held.
+func (b *RetryBudget) allows() bool {
+	oldest := b.index() - retryBudgetBuckets + 1
+	var firsts, retries uint64
+	for _, bucket := range b.buckets {
+		if bucket.index >= oldest {
+			firsts += bucket.firsts
+			retries += bucket.retries
+		}
+	}
+	allowed := b.Percent/100*float64(firsts) + b.MinRetriesPerSecond*b.window().Seconds()
+	return float64(retries+1) <= allowed
+}
+
+// current returns the bucket for now, emptying it if it was last used for an earlier piece of the window. Must be
+// called with the lock held.
+func (b *RetryBudget) current() *retryBudgetBucket {
+	index := b.index()
+	bucket := &b.buckets[index%retryBudgetBuckets]
+	if bucket.index != index {
+		*bucket = retryBudgetBucket{index: index}
+	}
+	return bucket
+}
+
+// index numbers the pieces of the window, so that each moment belongs to one of them
+func (b *RetryBudget) index() int64 {
+	width := b.window() / retryBudgetBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return clockOrDefault(b.Clock).Now().UnixNano() / int64(width)
+}
+
+func (b *RetryBudget) window() time.Duration {
+	if b.Window <= 0 {
+		return 10 * time.Second
+	}
+	return b.Window
+}
+
+// budgetService takes from the budget before each retry. Everything else is up to the wrapped Service.
+type budgetService struct {
+	wrapper
+	budget   *RetryBudget
+	attempts uint
+}
+
+// ShouldTry is false if the wrapped service is done, or if the budget has no retries left
+func (c *budgetService) ShouldTry() bool {
+	return c.Service.ShouldTry() && c.budget.canRetry()
+}
+
+// Reason explains why ShouldTry is false
+func (c *budgetService) Reason() error {
+	if reason := c.wrapper.Reason(); reason != nil {
+		return reason
+	}
+	if !c.budget.canRetry() {
+		return ErrBudgetExhausted
+	}
+	return nil
+}
+
+// AllowAttempt adds the first attempt to the budget and takes each retry from it
+func (c *budgetService) AllowAttempt() error {
+	var withdrawnFrom int64
+	if c.attempts == 0 {
+		c.budget.first()
+	} else {
+		index, ok := c.budget.withdraw()
+		if !ok {
+			// another caller took the last retry while this one was waiting
+			return ErrBudgetExhausted
+		}
+		withdrawnFrom = index
+	}
+	if err := c.wrapper.AllowAttempt(); err != nil {
+		if c.attempts > 0 {
+			c.budget.refund(withdrawnFrom)
+		}
+		return err
+	}
+	c.attempts++
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countAttempts retries a function that always fails and returns how many attempts it made and the errors
+func countAttempts(svc Service) (int, Errorer) {
+	attempts := 0
+	errList := How(svc).This(func(controller ServiceController) error {
+		attempts++
+		return errors.New("boom")
+	})
+	return attempts, errList
+}
+
+func TestRetryBudget_Percent(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	budget := &RetryBudget{Percent: 50, Window: 10 * time.Second, Clock: clock}
+	policy := MaxAttempts{Times: 5}
+
+	_ = How(budget.Wrap(policy.New())).This(alwaysSucceed)
+	_ = How(budget.Wrap(policy.New())).This(alwaysSucceed)
+
+	// 3 first attempts allow 1.5 retries
+	attempts, errList := countAttempts(budget.Wrap(policy.New()))
+	if attempts != 2 {
+		t.Errorf(`expected 2 attempts, got: %d`, attempts)
+	}
+	if errList.Reason() != ErrBudgetExhausted {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrBudgetExhausted, errList.Reason())
+	}
+
+	// 4 first attempts allow 2 retries, 1 has been used
+	attempts, _ = countAttempts(budget.Wrap(policy.New()))
+	if attempts != 2 {
+		t.Errorf(`expected 2 attempts, got: %d`, attempts)
+	}
+
+	// the window slides past every attempt so far, so there is only this call's first attempt to go on
+	clock.Advance(10 * time.Second)
+	attempts, _ = countAttempts(budget.Wrap(policy.New()))
+	if attempts != 1 {
+		t.Errorf(`expected 1 attempt, got: %d`, attempts)
+	}
+}
+
+func TestRetryBudget_MinRetriesPerSecond(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	budget := &RetryBudget{MinRetriesPerSecond: 0.2, Window: 10 * time.Second, Clock: clock}
+	policy := MaxAttempts{Times: 5}
+
+	attempts, _ := countAttempts(budget.Wrap(policy.New()))
+	if attempts != 3 {
+		t.Errorf(`expected 3 attempts, got: %d`, attempts)
+	}
+	attempts, _ = countAttempts(budget.Wrap(policy.New()))
+	if attempts != 1 {
+		t.Errorf(`expected 1 attempt, got: %d`, attempts)
+	}
+}
+
+func TestRetryBudget_ServiceStillDecides(t *testing.T) {
+	budget := &RetryBudget{MinRetriesPerSecond: 100}
+	attempts, errList := countAttempts(budget.Wrap(MaxAttempts{Times: 2}.New()))
+	if attempts != 2 {
+		t.Errorf(`expected 2 attempts, got: %d`, attempts)
+	}
+	if errList.Reason() != ErrExhausted {
+		t.Errorf(`expected reason: "%v" but got: "%v"`, ErrExhausted, errList.Reason())
+	}
+}
+
+func TestRetryBudget_Concurrent(t *testing.T) {
+	budget := &RetryBudget{Percent: 10, Window: 1 * time.Hour}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	retries := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempts, _ := countAttempts(budget.Wrap(MaxAttempts{Times: 3}.New()))
+			mu.Lock()
+			retries += attempts - 1
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if retries > 5 {
+		t.Errorf(`expected at most 5 retries for 50 calls, got: %d`, retries)
+	}
+}
+
+// TestRetryBudget_RefundAcrossBuckets ensures that a refund goes back to the bucket the retry was taken from
+func TestRetryBudget_RefundAcrossBuckets(t *testing.T) {
+	clock := retrytest.NewClock(time.Now())
+	budget := &RetryBudget{Percent: 100, Window: 10 * time.Second, Clock: clock}
+	budget.first()
+	budget.first()
+	withdrawnFrom, _ := budget.withdraw()
+
+	// the next bucket starts before the retry is given back
+	clock.Advance(1 * time.Second)
+	if _, ok := budget.withdraw(); !ok {
+		t.Fatal("expected a second retry to be allowed")
+	}
+	budget.refund(withdrawnFrom)
+	if retries := budget.buckets[withdrawnFrom%retryBudgetBuckets].retries; retries != 0 {
+		t.Errorf(`expected the refund to empty the bucket it was taken from, got: %d retries`, retries)
+	}
+	if retries := budget.buckets[(withdrawnFrom+1)%retryBudgetBuckets].retries; retries != 1 {
+		t.Errorf(`expected the current bucket to keep its retry, got: %d retries`, retries)
+	}
+
+	// once the bucket has left the window, a late refund must not touch the bucket that replaced it
+	withdrawnFrom = budget.current().index
+	clock.Advance(10 * time.Second)
+	budget.first()
+	replacement, _ := budget.withdraw()
+	budget.refund(withdrawnFrom)
+	if retries := budget.buckets[replacement%retryBudgetBuckets].retries; retries != 1 {
+		t.Errorf(`expected a refund from outside the window to be ignored, got: %d retries`, retries)
+	}
+}
@@ -15,7 +15,9 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"github.com/wojnosystems/retry/retrytest"
 	"testing"
 	"time"
 )
@@ -123,3 +125,171 @@ func TestRetry_Abort_NoYield(t *testing.T) {
 		t.Error("retry waited after abort was called and should not have")
 	}
 }
+
+// TestRetry_FakeClock ensures that a long back-off schedule can be tested without waiting in real time
+func TestRetry_FakeClock(t *testing.T) {
+	clock := retrytest.NewAutoClock(time.Now())
+	errList := How(ExpBase2{
+		Times:   5,
+		Scaling: 1 * time.Minute,
+		Clock:   clock,
+	}.New()).This(func(controller ServiceController) error {
+		return errors.New("boom")
+	})
+	if len(errList.Errors()) != 5 {
+		t.Errorf(`expected 5 errors, got: %d`, len(errList.Errors()))
+	}
+
+	expected := []time.Duration{1 * time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}
+	actual := clock.Waits()
+	if len(actual) != len(expected) {
+		t.Fatalf(`expected %d waits, got: %d`, len(expected), len(actual))
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Errorf(`expected duration: %v but got %v`, expected[i], actual[i])
+		}
+	}
+}
+
+// TestRetry_ThisContext_PassesContext ensures that each attempt receives the caller's context
+func TestRetry_ThisContext_PassesContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	attempts := 0
+	errList := How(MaxAttempts{Times: 2}.New()).ThisContext(ctx, func(ctx context.Context, controller ServiceController) error {
+		attempts++
+		if ctx.Value(key{}) != "value" {
+			t.Error("expected the caller's context to be passed to the attempt")
+		}
+		return errors.New("boom")
+	})
+	if attempts != 2 {
+		t.Errorf(`expected 2 attempts, got: %d`, attempts)
+	}
+	if errList == nil || len(errList.Errors()) != 2 {
+		t.Error("expected 2 errors")
+	}
+}
+
+// TestRetry_ThisContext_AlreadyDone ensures that no attempt is made once the context is done
+func TestRetry_ThisContext_AlreadyDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errList := How(MaxAttempts{Times: 3}.New()).ThisContext(ctx, func(ctx context.Context, controller ServiceController) error {
+		t.Error("expected no attempts to be made")
+		return nil
+	})
+	if errList == nil {
+		t.Fatal("expected an error list")
+	}
+	if errList.Last() != context.Canceled {
+		t.Errorf(`expected error: "%v" but got: "%v"`, context.Canceled, errList.Last())
+	}
+}
+
+// TestRetry_ThisContext_CancelDuringYield ensures that cancelling the context stops a Yield in progress and that no
+// more attempts are made
+func TestRetry_ThisContext_CancelDuringYield(t *testing.T) {
+	cases := map[string]func(clock Clock) Service{
+		"context yielder": func(clock Clock) Service {
+			return ExpBase2{Times: 5, Scaling: 10 * time.Minute, Clock: clock}.New()
+		},
+		"plain service": func(clock Clock) Service {
+			return &plainService{Service: ExpBase2{Times: 5, Scaling: 10 * time.Minute, Clock: clock}.New()}
+		},
+	}
+
+	for caseName, newService := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			clock := retrytest.NewClock(time.Now())
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			attempts := 0
+			done := make(chan Errorer)
+			go func() {
+				done <- How(newService(clock)).ThisContext(ctx, func(ctx context.Context, controller ServiceController) error {
+					attempts++
+					return errors.New("boom")
+				})
+			}()
+			clock.BlockUntil(1)
+			cancel()
+			errList := <-done
+			if attempts != 1 {
+				t.Errorf(`expected 1 attempt, got: %d`, attempts)
+			}
+			if errList == nil || errList.Last().Error() != "boom" {
+				t.Error(`expected the "boom" error to be returned`)
+			}
+		})
+	}
+}
+
+// plainService hides every optional interface of the Service it wraps
+type plainService struct {
+	Service
+}
+
+// TestRetry_Unlimited ensures that Unlimited keeps trying until told to stop
+func TestRetry_Unlimited(t *testing.T) {
+	cases := map[string]struct {
+		stop   func(controller ServiceController) error
+		reason error
+	}{
+		"abort": {
+			stop: func(controller ServiceController) error {
+				controller.Abort()
+				return errors.New("boom")
+			},
+			reason: ErrAborted,
+		},
+		"permanent": {
+			stop: func(controller ServiceController) error {
+				return Permanent(errors.New("boom"))
+			},
+			reason: ErrAborted,
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			tries := 0
+			errList := How(ExpBase2{
+				Times:   Unlimited,
+				Scaling: time.Hour,
+				Clock:   retrytest.NewAutoClock(time.Now()),
+			}.New()).This(func(controller ServiceController) error {
+				tries++
+				if tries == 100 {
+					return c.stop(controller)
+				}
+				return errors.New("boom")
+			})
+			if tries != 100 {
+				t.Errorf(`expected 100 tries, but got %d`, tries)
+			}
+			if !errors.Is(errList.Reason(), c.reason) {
+				t.Errorf(`expected reason: %v but got %v`, c.reason, errList.Reason())
+			}
+		})
+	}
+}
+
+// TestRetry_Unlimited_Success ensures that Unlimited stops once the attempt succeeds
+func TestRetry_Unlimited_Success(t *testing.T) {
+	tries := 0
+	errList := How(MaxAttempts{Times: Unlimited}.New()).This(func(controller ServiceController) error {
+		tries++
+		if tries == 10 {
+			return nil
+		}
+		return errors.New("boom")
+	})
+	if errList != nil {
+		t.Errorf(`expected no errors, but got %v`, errList)
+	}
+	if tries != 10 {
+		t.Errorf(`expected 10 tries, but got %d`, tries)
+	}
+}
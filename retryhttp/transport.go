@@ -0,0 +1,274 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package retryhttp retries HTTP requests using the retry package's Services
+package retryhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/wojnosystems/retry"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultRetryStatusCodes are the response status codes retried when Transport.RetryStatusCodes is nil
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultPolicy is used when Transport.Policy is nil: 3 attempts, waiting about 100ms then 200ms
+var DefaultPolicy retry.Factory = retry.ExpBase2{
+	Times:              3,
+	Scaling:            100 * time.Millisecond,
+	MaxAttemptWaitTime: 5 * time.Second,
+	Jitter:             retry.Jitter{Mode: retry.JitterEqual},
+}
+
+// maxDrainBytes is how much of a discarded response body is read so the connection can be re-used
+const maxDrainBytes = 4 << 10
+
+// Transport is an http.RoundTripper that retries requests that fail to connect or receive a retryable status code.
+// Errors that trying again will not fix, such as an unsupported URL scheme or an untrusted certificate, are not retried.
+// Wait times come from the Service created by Policy for each request, but a Retry-After header is honoured.
+type Transport struct {
+	// Base performs each attempt. Leave nil to use http.DefaultTransport
+	Base http.RoundTripper
+
+	// Policy creates the Service that decides when to retry each request. Leave nil to use DefaultPolicy. Do not set an
+	// AttemptTimeout on it: the attempt's context ends with the attempt, which would also cut off the response body.
+	// Use http.Client.Timeout or the request's context instead.
+	Policy retry.Factory
+
+	// RetryStatusCodes are the response status codes to retry. Leave nil to use DefaultRetryStatusCodes
+	RetryStatusCodes []int
+
+	// RetryNonIdempotent allows POST, PATCH and CONNECT requests to be retried. By default, only methods that are
+	// idempotent, or requests with an Idempotency-Key header, are retried.
+	RetryNonIdempotent bool
+}
+
+// StatusError is recorded for each attempt that received a retryable status code
+type StatusError struct {
+	// StatusCode is the code the server responded with
+	StatusCode int
+
+	// Status is the status line the server responded with, e.g. "503 Service Unavailable"
+	Status string
+
+	// After is how long the server asked to wait in its Retry-After header, or 0 if it did not
+	After time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("retryable response status: %s", e.Status)
+}
+
+// RetryAfter makes the retry wait at least as long as the server asked
+func (e *StatusError) RetryAfter() time.Duration {
+	return e.After
+}
+
+// RoundTrip sends the request, retrying if it is safe to do so. If every attempt received a retryable status code,
+// the last response is returned as-is so the caller can inspect it. Otherwise, the retry.Errorer is returned.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.canRetry(req) {
+		return t.base().RoundTrip(req)
+	}
+
+	var resp *http.Response
+	sent := false
+	svc := t.policy().New()
+	now := nowOf(svc)
+	errList := retry.How(svc).ThisContext(req.Context(), func(ctx context.Context, controller retry.ServiceController) error {
+		if resp != nil {
+			// about to try again, this response is no longer needed
+			discard(resp)
+			resp = nil
+		}
+		attemptReq := req.Clone(ctx)
+		if sent && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return retry.Permanent(err)
+			}
+			attemptReq.Body = body
+		}
+		sent = true
+		attemptResp, err := t.base().RoundTrip(attemptReq)
+		if err != nil {
+			if !transient(err) {
+				return retry.Permanent(err)
+			}
+			return err
+		}
+		resp = attemptResp
+		if t.retryStatus(resp.StatusCode) {
+			return &StatusError{
+				StatusCode: resp.StatusCode,
+				Status:     resp.Status,
+				After:      parseRetryAfter(resp.Header.Get("Retry-After"), now()),
+			}
+		}
+		return nil
+	})
+	if !sent && req.Body != nil {
+		// the transport never got to close the body for us
+		_ = req.Body.Close()
+	}
+	if errList == nil {
+		return resp, nil
+	}
+	if resp != nil {
+		if req.Context().Err() == nil {
+			// out of retries, let the caller see what the server said last
+			return resp, nil
+		}
+		discard(resp)
+	}
+	return nil, errList
+}
+
+// canRetry is true if the request can be sent more than once
+func (t *Transport) canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// no way to rewind the body
+		return false
+	}
+	return t.RetryNonIdempotent || isIdempotent(req)
+}
+
+// retryStatus is true if the status code is one that should be retried
+func (t *Transport) retryStatus(code int) bool {
+	codes := t.RetryStatusCodes
+	if codes == nil {
+		codes = DefaultRetryStatusCodes
+	}
+	for _, retryCode := range codes {
+		if retryCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *Transport) policy() retry.Factory {
+	if t.Policy == nil {
+		return DefaultPolicy
+	}
+	return t.Policy
+}
+
+// transient is true for errors that may go away by trying again, such as a refused or reset connection
+func transient(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthority), errors.As(err, &hostnameErr), errors.As(err, &invalidCert):
+		// the server will present the same certificate next time
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// the retry stops on its own once the request's context is done, this keeps the reason accurate
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		// the name may appear, but not if the DNS server said it does not exist
+		return !dnsErr.IsNotFound
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isIdempotent is true if sending the request more than once has the same effect as sending it once
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	// the same convention that net/http uses to decide if a request can be retried
+	_, hasKey := req.Header["Idempotency-Key"]
+	_, hasXKey := req.Header["X-Idempotency-Key"]
+	return hasKey || hasXKey
+}
+
+// nowOf tells the time with the Service's Clock, so a Retry-After date is measured the same way as its waits. Falls
+// back to the system clock if the Service does not have one.
+func nowOf(svc retry.Service) func() time.Time {
+	if clocked, ok := svc.(interface{ Clock() retry.Clock }); ok {
+		if clock := clocked.Clock(); clock != nil {
+			return clock.Now
+		}
+	}
+	return time.Now
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of seconds or an HTTP date. Returns 0 if the
+// header is missing or cannot be understood.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		if seconds > math.MaxInt64/int64(time.Second) {
+			return math.MaxInt64
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// discard reads a little of the body, so the connection can be re-used, and then closes it
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,323 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retryhttp
+
+import (
+	"errors"
+	"github.com/wojnosystems/retry"
+	"github.com/wojnosystems/retry/retrytest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// respondWith answers each request with the next status code, or 200 once they run out
+func respondWith(t *testing.T, codes ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1))
+		if call <= len(codes) {
+			w.WriteHeader(codes[call-1])
+			_, _ = io.WriteString(w, "not yet")
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func instantPolicy(clock retry.Clock) retry.Factory {
+	return retry.ExpBase2{Times: 3, Scaling: 1 * time.Second, Clock: clock}
+}
+
+func TestTransport_RetriesStatusCodes(t *testing.T) {
+	server, calls := respondWith(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+	client := &http.Client{Transport: &Transport{Policy: instantPolicy(retrytest.NewAutoClock(time.Now()))}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf(`expected no error, got: "%v"`, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf(`expected the successful response, got: %d "%s"`, resp.StatusCode, body)
+	}
+	if *calls != 3 {
+		t.Errorf(`expected 3 calls, got: %d`, *calls)
+	}
+}
+
+func TestTransport_ReturnsLastResponse(t *testing.T) {
+	server, calls := respondWith(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	client := &http.Client{Transport: &Transport{Policy: instantPolicy(retrytest.NewAutoClock(time.Now()))}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf(`expected no error, got: "%v"`, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusServiceUnavailable || string(body) != "not yet" {
+		t.Errorf(`expected the last response, got: %d "%s"`, resp.StatusCode, body)
+	}
+	if *calls != 3 {
+		t.Errorf(`expected 3 calls, got: %d`, *calls)
+	}
+}
+
+func TestTransport_DoesNotRetryOtherStatusCodes(t *testing.T) {
+	server, calls := respondWith(t, http.StatusInternalServerError)
+	client := &http.Client{Transport: &Transport{Policy: instantPolicy(retrytest.NewAutoClock(time.Now()))}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf(`expected no error, got: "%v"`, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || *calls != 1 {
+		t.Errorf(`expected a single 500 response, got: %d after %d calls`, resp.StatusCode, *calls)
+	}
+}
+
+func TestTransport_Idempotency(t *testing.T) {
+	cases := map[string]struct {
+		transport Transport
+		header    http.Header
+		expected  int32
+	}{
+		"POST is not retried": {
+			expected: 1,
+		},
+		"POST with an idempotency key": {
+			header:   http.Header{"Idempotency-Key": []string{"abc"}},
+			expected: 2,
+		},
+		"POST when non-idempotent requests are allowed": {
+			transport: Transport{RetryNonIdempotent: true},
+			expected:  2,
+		},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			var bodies []string
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				if atomic.AddInt32(&calls, 1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+			transport := c.transport
+			transport.Policy = instantPolicy(retrytest.NewAutoClock(time.Now()))
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+			for key, values := range c.header {
+				req.Header[key] = values
+			}
+
+			resp, err := (&http.Client{Transport: &transport}).Do(req)
+			if err != nil {
+				t.Fatalf(`expected no error, got: "%v"`, err)
+			}
+			resp.Body.Close()
+			if calls != c.expected {
+				t.Errorf(`expected %d calls, got: %d`, c.expected, calls)
+			}
+			for _, body := range bodies {
+				if body != "payload" {
+					t.Errorf(`expected every attempt to send the whole body, got: "%s"`, body)
+				}
+			}
+		})
+	}
+}
+
+func TestTransport_NoGetBody(t *testing.T) {
+	server, calls := respondWith(t, http.StatusServiceUnavailable)
+	req, _ := http.NewRequest(http.MethodPut, server.URL, io.NopCloser(strings.NewReader("payload")))
+	resp, err := (&Transport{Policy: instantPolicy(retrytest.NewAutoClock(time.Now()))}).RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`expected no error, got: "%v"`, err)
+	}
+	resp.Body.Close()
+	if *calls != 1 {
+		t.Errorf(`expected a body that cannot be rewound to be sent once, got: %d calls`, *calls)
+	}
+}
+
+func TestTransport_RetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+	clock := retrytest.NewAutoClock(time.Now())
+
+	resp, err := (&http.Client{Transport: &Transport{Policy: instantPolicy(clock)}}).Get(server.URL)
+	if err != nil {
+		t.Fatalf(`expected no error, got: "%v"`, err)
+	}
+	resp.Body.Close()
+	waits := clock.Waits()
+	if len(waits) != 1 || waits[0] != 30*time.Second {
+		t.Errorf(`expected to wait 30s, got: %v`, waits)
+	}
+}
+
+// TestTransport_RetryAfterDate ensures that a Retry-After date is measured with the policy's clock
+func TestTransport_RetryAfterDate(t *testing.T) {
+	clock := retrytest.NewAutoClock(time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC))
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", clock.Now().Add(30*time.Second).Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := (&http.Client{Transport: &Transport{Policy: instantPolicy(clock)}}).Get(server.URL)
+	if err != nil {
+		t.Fatalf(`expected no error, got: "%v"`, err)
+	}
+	resp.Body.Close()
+	waits := clock.Waits()
+	if len(waits) != 1 || waits[0] != 30*time.Second {
+		t.Errorf(`expected to wait 30s, got: %v`, waits)
+	}
+}
+
+func TestTransport_ConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	_, err := (&http.Client{Transport: &Transport{Policy: instantPolicy(retrytest.NewAutoClock(time.Now()))}}).Get(server.URL)
+	var errList retry.Errorer
+	if !errors.As(err, &errList) {
+		t.Fatalf(`expected a retry.Errorer, got: "%v"`, err)
+	}
+	if len(errList.Errors()) != 3 {
+		t.Errorf(`expected 3 attempts, got: %d`, len(errList.Errors()))
+	}
+}
+
+func TestTransport_PermanentErrors(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(tlsServer.Close)
+
+	cases := map[string]string{
+		"unsupported scheme":    "foo://example.com/",
+		"untrusted certificate": tlsServer.URL,
+	}
+
+	for caseName, url := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			var calls int32
+			base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&calls, 1)
+				return http.DefaultTransport.RoundTrip(req)
+			})
+			_, err := (&http.Client{Transport: &Transport{Base: base, Policy: instantPolicy(retrytest.NewAutoClock(time.Now()))}}).Get(url)
+			var errList retry.Errorer
+			if !errors.As(err, &errList) {
+				t.Fatalf(`expected a retry.Errorer, got: "%v"`, err)
+			}
+			if calls != 1 {
+				t.Errorf(`expected 1 attempt, got: %d`, calls)
+			}
+			if !errors.Is(errList.Reason(), retry.ErrAborted) {
+				t.Errorf(`expected the error to stop retrying, got reason: %v`, errList.Reason())
+			}
+		})
+	}
+}
+
+// trackingBody records whether it was closed
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+// roundTripFunc adapts a function to an http.RoundTripper
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransport_ClosesDiscardedBodies(t *testing.T) {
+	var bodies []*trackingBody
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := &trackingBody{Reader: strings.NewReader("not yet")}
+		bodies = append(bodies, body)
+		code := http.StatusServiceUnavailable
+		if len(bodies) == 3 {
+			code = http.StatusOK
+		}
+		return &http.Response{StatusCode: code, Status: http.StatusText(code), Body: body, Header: http.Header{}}, nil
+	})
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+
+	resp, err := (&Transport{Base: base, Policy: instantPolicy(retrytest.NewAutoClock(time.Now()))}).RoundTrip(req)
+	if err != nil {
+		t.Fatalf(`expected no error, got: "%v"`, err)
+	}
+	if !bodies[0].closed || !bodies[1].closed {
+		t.Error("expected the discarded responses to be closed")
+	}
+	if bodies[2].closed || resp.Body != bodies[2] {
+		t.Error("expected the returned response to be left open for the caller")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		value    string
+		expected time.Duration
+	}{
+		"missing":  {value: "", expected: 0},
+		"seconds":  {value: "120", expected: 2 * time.Minute},
+		"negative": {value: "-5", expected: 0},
+		"date":     {value: "Tue, 01 Jan 2019 00:01:30 GMT", expected: 90 * time.Second},
+		"past":     {value: "Mon, 31 Dec 2018 23:00:00 GMT", expected: 0},
+		"garbage":  {value: "soon", expected: 0},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			actual := parseRetryAfter(c.value, now)
+			if actual != c.expected {
+				t.Errorf(`expected duration: %v but got %v`, c.expected, actual)
+			}
+		})
+	}
+}
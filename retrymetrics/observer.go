@@ -0,0 +1,140 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package retrymetrics counts and measures retries. Observer plugs into retry.How and writes to any Sink. Registry is
+// a Sink that serves the Prometheus text format without any other dependencies.
+package retrymetrics
+
+import (
+	"errors"
+	"github.com/wojnosystems/retry"
+	"strconv"
+)
+
+// Names of the metrics written by Observer
+const (
+	// MetricAttempts counts every attempt, including the first
+	MetricAttempts = "retry_attempts_total"
+
+	// MetricRetries counts every attempt after the first
+	MetricRetries = "retry_retries_total"
+
+	// MetricGiveUps counts calls that stopped without success, labelled by reason
+	MetricGiveUps = "retry_give_ups_total"
+
+	// MetricSuccesses counts calls that succeeded, labelled by the attempt that succeeded. Later attempts share the
+	// label of Observer.MaxAttemptLabel, e.g. "5+".
+	MetricSuccesses = "retry_successes_total"
+
+	// MetricAttemptsPerCall is a histogram of how many attempts each call made
+	MetricAttemptsPerCall = "retry_attempts_per_call"
+
+	// MetricYieldSeconds is a histogram of the time spent waiting between attempts
+	MetricYieldSeconds = "retry_yield_seconds"
+)
+
+// Labels are the names and values that identify a series of a metric
+type Labels map[string]string
+
+// Sink receives the metrics. Implement it to send retry metrics to your own backend.
+type Sink interface {
+	// AddCounter increases the counter with the name and labels by delta
+	AddCounter(name string, labels Labels, delta float64)
+
+	// ObserveHistogram records value in the histogram with the name and labels
+	ObserveHistogram(name string, labels Labels, value float64)
+}
+
+// Observer is a retry.Observer that writes metrics to Sink, labelled with Operation
+type Observer struct {
+	// Operation names what is being retried, it is the "operation" label of every metric
+	Operation string
+
+	// Sink receives the metrics
+	Sink Sink
+
+	// MaxAttemptLabel is the highest attempt given its own "attempt" label on MetricSuccesses. Successes at or after it
+	// are labelled with it and a "+", so the number of series stays bounded. Leave as 0 to use 5.
+	MaxAttemptLabel uint
+}
+
+// OnAttempt counts the attempt and the time spent waiting for it
+func (o Observer) OnAttempt(e retry.Event) {
+	labels := o.labels()
+	o.Sink.AddCounter(MetricAttempts, labels, 1)
+	if e.Attempt > 1 {
+		o.Sink.AddCounter(MetricRetries, labels, 1)
+		o.Sink.ObserveHistogram(MetricYieldSeconds, labels, e.Waited.Seconds())
+	}
+}
+
+// OnRetry does nothing, the next attempt is counted instead
+func (o Observer) OnRetry(e retry.Event) {
+}
+
+// OnGiveUp counts the give up by reason
+func (o Observer) OnGiveUp(e retry.Event) {
+	if e.Waited > 0 {
+		// gave up while waiting
+		o.Sink.ObserveHistogram(MetricYieldSeconds, o.labels(), e.Waited.Seconds())
+	}
+	o.Sink.ObserveHistogram(MetricAttemptsPerCall, o.labels(), float64(e.Attempt))
+	labels := o.labels()
+	labels["reason"] = ReasonLabel(e.Reason)
+	o.Sink.AddCounter(MetricGiveUps, labels, 1)
+}
+
+// OnSuccess counts the success by the attempt that succeeded
+func (o Observer) OnSuccess(e retry.Event) {
+	o.Sink.ObserveHistogram(MetricAttemptsPerCall, o.labels(), float64(e.Attempt))
+	labels := o.labels()
+	labels["attempt"] = o.attemptLabel(e.Attempt)
+	o.Sink.AddCounter(MetricSuccesses, labels, 1)
+}
+
+// attemptLabel is the attempt number, or MaxAttemptLabel and a "+" for later attempts
+func (o Observer) attemptLabel(attempt uint) string {
+	max := o.MaxAttemptLabel
+	if max == 0 {
+		max = 5
+	}
+	if attempt >= max {
+		return strconv.FormatUint(uint64(max), 10) + "+"
+	}
+	return strconv.FormatUint(uint64(attempt), 10)
+}
+
+func (o Observer) labels() Labels {
+	return Labels{"operation": o.Operation}
+}
+
+// ReasonLabel turns a retry.Errorer's Reason into a short label value
+func ReasonLabel(reason error) string {
+	switch {
+	case errors.Is(reason, retry.ErrExhausted):
+		return "exhausted"
+	case errors.Is(reason, retry.ErrAborted):
+		return "aborted"
+	case errors.Is(reason, retry.ErrContextDone):
+		return "context_done"
+	case errors.Is(reason, retry.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(reason, retry.ErrBudgetExhausted):
+		return "budget_exhausted"
+	case errors.Is(reason, retry.ErrDeadline):
+		return "deadline"
+	default:
+		return "other"
+	}
+}
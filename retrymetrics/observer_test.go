@@ -0,0 +1,118 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retrymetrics
+
+import (
+	"context"
+	"errors"
+	"github.com/wojnosystems/retry"
+	"github.com/wojnosystems/retry/retrytest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObserver(t *testing.T) {
+	registry := &Registry{}
+	clock := retrytest.NewAutoClock(time.Now())
+	policy := retry.ExpBase2{Times: 3, Scaling: 1 * time.Second, Clock: clock}
+
+	// fails twice, then succeeds
+	attempt := 0
+	_ = retry.How(policy.New(), Observer{Operation: "fetch", Sink: registry}).This(func(controller retry.ServiceController) error {
+		attempt++
+		if attempt < 3 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	// gives up
+	_ = retry.How(policy.New(), Observer{Operation: "fetch", Sink: registry}).This(func(controller retry.ServiceController) error {
+		return errors.New("boom")
+	})
+
+	var out strings.Builder
+	if err := registry.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	expectedLines := []string{
+		`retry_attempts_total{operation="fetch"} 6`,
+		`retry_retries_total{operation="fetch"} 4`,
+		`retry_successes_total{attempt="3",operation="fetch"} 1`,
+		`retry_give_ups_total{operation="fetch",reason="exhausted"} 1`,
+		`retry_attempts_per_call_count{operation="fetch"} 2`,
+		`retry_attempts_per_call_sum{operation="fetch"} 6`,
+		`retry_yield_seconds_count{operation="fetch"} 4`,
+		`retry_yield_seconds_sum{operation="fetch"} 6`,
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf(`expected line "%s" in:
+%s`, line, out.String())
+		}
+	}
+}
+
+func TestReasonLabel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errList := retry.How(retry.MaxAttempts{Times: 1}.New()).ThisContext(ctx, func(ctx context.Context, controller retry.ServiceController) error {
+		return nil
+	})
+
+	cases := map[string]struct {
+		reason   error
+		expected string
+	}{
+		"exhausted":    {reason: retry.ErrExhausted, expected: "exhausted"},
+		"aborted":      {reason: retry.ErrAborted, expected: "aborted"},
+		"context done": {reason: errList.Reason(), expected: "context_done"},
+		"circuit open": {reason: retry.ErrCircuitOpen, expected: "circuit_open"},
+		"budget":       {reason: retry.ErrBudgetExhausted, expected: "budget_exhausted"},
+		"deadline":     {reason: retry.ErrDeadline, expected: "deadline"},
+		"unknown":      {reason: errors.New("mine"), expected: "other"},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			if actual := ReasonLabel(c.reason); actual != c.expected {
+				t.Errorf(`expected label: "%s" but got: "%s"`, c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestObserver_AttemptLabel(t *testing.T) {
+	cases := map[string]struct {
+		observer Observer
+		attempt  uint
+		expected string
+	}{
+		"first":               {attempt: 1, expected: "1"},
+		"below the default":   {attempt: 4, expected: "4"},
+		"at the default":      {attempt: 5, expected: "5+"},
+		"past the default":    {attempt: 1000, expected: "5+"},
+		"configured":          {observer: Observer{MaxAttemptLabel: 3}, attempt: 2, expected: "2"},
+		"past the configured": {observer: Observer{MaxAttemptLabel: 3}, attempt: 7, expected: "3+"},
+	}
+
+	for caseName, c := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			if actual := c.observer.attemptLabel(c.attempt); actual != c.expected {
+				t.Errorf(`expected label: "%s" but got: "%s"`, c.expected, actual)
+			}
+		})
+	}
+}
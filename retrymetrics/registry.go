@@ -0,0 +1,234 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retrymetrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds used by Registry for the metrics written by Observer
+var DefaultBuckets = map[string][]float64{
+	MetricAttemptsPerCall: {1, 2, 3, 4, 5, 7, 10, 15, 20},
+	MetricYieldSeconds:    {0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300},
+}
+
+// fallbackBuckets are used for histograms that have no buckets configured
+var fallbackBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// help describes the metrics written by Observer
+var help = map[string]string{
+	MetricAttempts:        "Attempts made, including the first.",
+	MetricRetries:         "Attempts made after the first.",
+	MetricGiveUps:         "Calls that stopped without success, by reason.",
+	MetricSuccesses:       "Calls that succeeded, by the attempt that succeeded.",
+	MetricAttemptsPerCall: "Attempts made by each call.",
+	MetricYieldSeconds:    "Time spent waiting between attempts.",
+}
+
+// Registry is a Sink that keeps the metrics in memory and serves them in the Prometheus text exposition format. The
+// zero value is ready to use, and it is safe for concurrent use.
+type Registry struct {
+	// Buckets sets the histogram upper bounds by metric name. Leave nil to use DefaultBuckets. Do not change it after
+	// the Registry is first used.
+	Buckets map[string][]float64
+
+	mu         sync.Mutex
+	counters   map[string]map[string]*counter
+	histograms map[string]map[string]*histogram
+}
+
+// counter is one series of a counter
+type counter struct {
+	labels Labels
+	value  float64
+}
+
+// histogram is one series of a histogram
+type histogram struct {
+	labels Labels
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// AddCounter increases the counter with the name and labels by delta
+func (r *Registry) AddCounter(name string, labels Labels, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counters == nil {
+		r.counters = make(map[string]map[string]*counter)
+	}
+	series, ok := r.counters[name]
+	if !ok {
+		series = make(map[string]*counter)
+		r.counters[name] = series
+	}
+	key := formatLabels(labels)
+	c, ok := series[key]
+	if !ok {
+		c = &counter{labels: copyLabels(labels)}
+		series[key] = c
+	}
+	c.value += delta
+}
+
+// ObserveHistogram records value in the histogram with the name and labels
+func (r *Registry) ObserveHistogram(name string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.histograms == nil {
+		r.histograms = make(map[string]map[string]*histogram)
+	}
+	series, ok := r.histograms[name]
+	if !ok {
+		series = make(map[string]*histogram)
+		r.histograms[name] = series
+	}
+	key := formatLabels(labels)
+	h, ok := series[key]
+	if !ok {
+		bounds := r.bucketsFor(name)
+		h = &histogram{
+			labels: copyLabels(labels),
+			bounds: bounds,
+			counts: make([]uint64, len(bounds)),
+		}
+		series[key] = h
+	}
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// bucketsFor returns the upper bounds for the histogram with the name
+func (r *Registry) bucketsFor(name string) []float64 {
+	buckets := r.Buckets
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	if bounds, ok := buckets[name]; ok {
+		return bounds
+	}
+	return fallbackBuckets
+}
+
+// ServeHTTP writes every metric in the Prometheus text exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.Write(w)
+}
+
+// Write writes every metric in the Prometheus text exposition format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	for _, name := range sortedKeys(r.counters) {
+		writeHeader(&b, name, "counter")
+		series := r.counters[name]
+		for _, key := range sortedKeys(series) {
+			fmt.Fprintf(&b, "%s%s %s\n", name, key, formatFloat(series[key].value))
+		}
+	}
+	for _, name := range sortedKeys(r.histograms) {
+		writeHeader(&b, name, "histogram")
+		series := r.histograms[name]
+		for _, key := range sortedKeys(series) {
+			h := series[key]
+			for i, bound := range h.bounds {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels(withLabel(h.labels, "le", formatFloat(bound))), h.counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels(withLabel(h.labels, "le", "+Inf")), h.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, key, formatFloat(h.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, key, h.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeHeader writes the HELP, if known, and TYPE lines
+func writeHeader(b *strings.Builder, name, metricType string) {
+	if text, ok := help[name]; ok {
+		fmt.Fprintf(b, "# HELP %s %s\n", name, text)
+	}
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, metricType)
+}
+
+// formatLabels writes the labels as {name="value",...} sorted by name, or nothing if there are none
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := sortedKeys(labels)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabelValue(labels[name]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func copyLabels(labels Labels) Labels {
+	copied := make(Labels, len(labels))
+	for name, value := range labels {
+		copied[name] = value
+	}
+	return copied
+}
+
+func withLabel(labels Labels, name, value string) Labels {
+	with := copyLabels(labels)
+	with[name] = value
+	return with
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
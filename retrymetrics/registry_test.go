@@ -0,0 +1,75 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retrymetrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	registry := &Registry{
+		Buckets: map[string][]float64{"custom_seconds": {0.5, 1}},
+	}
+	registry.AddCounter(MetricAttempts, Labels{"operation": "b"}, 1)
+	registry.AddCounter(MetricAttempts, Labels{"operation": "a"}, 2)
+	registry.AddCounter(MetricAttempts, Labels{"operation": "a"}, 1)
+	registry.AddCounter("custom_total", Labels{"operation": `quote " and \ slash`}, 1)
+	registry.ObserveHistogram("custom_seconds", nil, 0.25)
+	registry.ObserveHistogram("custom_seconds", nil, 0.75)
+	registry.ObserveHistogram("custom_seconds", nil, 2)
+
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	expected := `# TYPE custom_total counter
+custom_total{operation="quote \" and \\ slash"} 1
+# HELP retry_attempts_total Attempts made, including the first.
+# TYPE retry_attempts_total counter
+retry_attempts_total{operation="a"} 3
+retry_attempts_total{operation="b"} 1
+# TYPE custom_seconds histogram
+custom_seconds_bucket{le="0.5"} 1
+custom_seconds_bucket{le="1"} 2
+custom_seconds_bucket{le="+Inf"} 3
+custom_seconds_sum 3
+custom_seconds_count 3
+`
+	if string(body) != expected {
+		t.Errorf(`expected:
+%s
+but got:
+%s`, expected, body)
+	}
+	if resp.Header.Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf(`unexpected content type: "%s"`, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestRegistry_Empty(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	(&Registry{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Body.Len() != 0 {
+		t.Errorf(`expected no metrics, got: "%s"`, recorder.Body.String())
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package retrytest provides helpers for testing code that uses retry Services without waiting in real time
+package retrytest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake retry.Clock. Time only moves forward when Advance is called, or, for a clock created with
+// NewAutoClock, whenever something waits on it. Every wait requested of the clock is recorded so tests can check the
+// back-off schedule exactly.
+type Clock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	auto    bool
+	waits   []time.Duration
+	timers  []*timer
+}
+
+// timer is a pending wait on the clock
+type timer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewClock creates a fake clock set to start that only moves when Advance is called
+func NewClock(start time.Time) *Clock {
+	c := &Clock{
+		now: start,
+	}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// NewAutoClock creates a fake clock set to start that immediately moves forward by however long anything waits on it.
+// Use this when you only care about which waits were requested and not about what happens during them.
+func NewAutoClock(start time.Time) *Clock {
+	c := NewClock(start)
+	c.auto = true
+	return c
+}
+
+// Now returns the fake time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep blocks until the clock has been advanced by at least d
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// After returns a channel that receives the fake time once the clock has been advanced by at least d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	t := &timer{
+		at: c.now.Add(d),
+		ch: make(chan time.Time, 1),
+	}
+	if c.auto && d > 0 {
+		c.now = t.at
+	}
+	if !t.at.After(c.now) {
+		t.ch <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+	c.changed.Broadcast()
+	return t.ch
+}
+
+// Advance moves the clock forward by d and fires every wait that has now elapsed
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.ch <- c.now
+		}
+	}
+	c.timers = pending
+	c.changed.Broadcast()
+}
+
+// BlockUntil waits until at least n waits are pending on the clock. Use this to synchronize with a goroutine that is
+// about to wait before calling Advance.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// Waits returns every duration that was waited on, in the order requested
+func (c *Clock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waits := make([]time.Duration, len(c.waits))
+	copy(waits, c.waits)
+	return waits
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retrytest
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TestClock_Advance ensures that waits only complete once the clock has moved far enough
+func TestClock_Advance(t *testing.T) {
+	c := NewClock(epoch)
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(10 * time.Second)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(9 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("sleep returned before the clock was advanced far enough")
+	default:
+	}
+	c.Advance(1 * time.Second)
+	<-done
+
+	if !c.Now().Equal(epoch.Add(10 * time.Second)) {
+		t.Errorf(`expected time: %v but got %v`, epoch.Add(10*time.Second), c.Now())
+	}
+}
+
+// TestClock_After_NoWait ensures that waiting for nothing does not block
+func TestClock_After_NoWait(t *testing.T) {
+	c := NewClock(epoch)
+	at := <-c.After(0)
+	if !at.Equal(epoch) {
+		t.Errorf(`expected time: %v but got %v`, epoch, at)
+	}
+}
+
+// TestAutoClock ensures that an auto clock moves forward on its own and records every wait
+func TestAutoClock(t *testing.T) {
+	c := NewAutoClock(epoch)
+	c.Sleep(10 * time.Minute)
+	<-c.After(5 * time.Minute)
+
+	if !c.Now().Equal(epoch.Add(15 * time.Minute)) {
+		t.Errorf(`expected time: %v but got %v`, epoch.Add(15*time.Minute), c.Now())
+	}
+	expected := []time.Duration{10 * time.Minute, 5 * time.Minute}
+	actual := c.Waits()
+	if len(actual) != len(expected) {
+		t.Fatalf(`expected %d waits but got %d`, len(expected), len(actual))
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf(`expected wait: %v but got %v`, expected[i], actual[i])
+		}
+	}
+}
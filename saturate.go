@@ -0,0 +1,61 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"math"
+	"time"
+)
+
+// These helpers do arithmetic on durations that stops at the largest duration rather than wrapping around to a
+// negative one. Waits are never negative, so they stop at 0 on the way down too.
+
+// saturatingAdd returns a + b, or the largest duration if that would overflow
+func saturatingAdd(a, b time.Duration) time.Duration {
+	if b < 0 {
+		// cannot overflow upwards, but do not go below 0
+		if a+b < 0 {
+			return 0
+		}
+		return a + b
+	}
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// saturatingMul returns a * b, or the largest duration if that would overflow
+func saturatingMul(a, b time.Duration) time.Duration {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	if a > math.MaxInt64/b {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+// saturatingFloat converts f to a duration, or the largest duration if it is too big. Negative values and NaN
+// become 0.
+func saturatingFloat(f float64) time.Duration {
+	if f >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if !(f > 0) {
+		return 0
+	}
+	return time.Duration(f)
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"strconv"
+)
+
+// SlogObserver is an Observer that logs failed attempts, give-ups and late successes with log/slog. Each record has
+// the attributes: operation, attempt, elapsed and, where they apply, wait, error and reason. A give-up record also has
+// an "errors" group with every error kept, keyed by the number of the attempt that returned it, and "dropped" if the
+// error list did not keep them all. Errors that did not come from an attempt, such as ErrCircuitOpen, are keyed by the
+// attempt they followed, e.g. "after 3". If the error list is not an AttemptHistory, the errors are keyed by their
+// position in the list instead.
+type SlogObserver struct {
+	// Logger receives the records. Leave nil to use slog.Default()
+	Logger *slog.Logger
+
+	// Operation names what is being retried, so records from different retries can be told apart
+	Operation string
+
+	// RetryLevel is the level to log failed attempts that will be retried at. Leave nil to use slog.LevelWarn
+	RetryLevel slog.Leveler
+
+	// GiveUpLevel is the level to log giving up at. Leave nil to use slog.LevelError
+	GiveUpLevel slog.Leveler
+
+	// SuccessLevel is the level to log a success that needed more than one attempt at. Leave nil to use slog.LevelInfo
+	SuccessLevel slog.Leveler
+}
+
+// OnAttempt does not log anything, the outcome of the attempt is logged instead
+func (o SlogObserver) OnAttempt(e Event) {
+}
+
+// OnRetry logs the failed attempt and how long until the next one
+func (o SlogObserver) OnRetry(e Event) {
+	o.log(o.RetryLevel, slog.LevelWarn, "attempt failed, retrying", e,
+		slog.Duration("wait", e.Wait),
+		slog.Any("error", e.Err),
+	)
+}
+
+// OnGiveUp logs the reason retrying stopped along with every error recorded
+func (o SlogObserver) OnGiveUp(e Event) {
+	attrs := []slog.Attr{
+		slog.Any("error", e.Err),
+		slog.Any("reason", e.Reason),
+	}
+	if e.Errors != nil {
+		attrs = append(attrs, slog.Group("errors", errorHistory(e.Errors)...))
+		if dropped := Dropped(e.Errors); dropped > 0 {
+			attrs = append(attrs, slog.Uint64("dropped", uint64(dropped)))
+		}
+	}
+	o.log(o.GiveUpLevel, slog.LevelError, "giving up retrying", e, attrs...)
+}
+
+// errorHistory keys each error by the attempt that returned it, or by its position if the attempts are not known.
+// Errors that did not come from an attempt, such as a refusal by an AttemptGate or a context being done, are keyed
+// by the attempt they followed, e.g. "after 3", or "before 1" if there was none.
+func errorHistory(errs Errorer) []any {
+	recorded := errs.Errors()
+	history := make([]any, len(recorded))
+	attempts, ok := errs.(AttemptHistory)
+	if !ok {
+		for i, err := range recorded {
+			history[i] = slog.Any(strconv.Itoa(i+1), err)
+		}
+		return history
+	}
+	kept := attempts.Attempts()
+	var latest uint
+	for i, err := range recorded {
+		if len(kept) > 0 && sameError(kept[0].Err, err) {
+			latest = kept[0].Number
+			kept = kept[1:]
+			history[i] = slog.Any(strconv.FormatUint(uint64(latest), 10), err)
+			continue
+		}
+		key := "before 1"
+		if latest > 0 {
+			key = "after " + strconv.FormatUint(uint64(latest), 10)
+		}
+		history[i] = slog.Any(key, err)
+	}
+	return history
+}
+
+// sameError is true if a and b are the same error, without panicking on error types that cannot be compared
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	errType := reflect.TypeOf(a)
+	if errType != reflect.TypeOf(b) || !errType.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// OnSuccess logs a success, but only if it needed more than one attempt
+func (o SlogObserver) OnSuccess(e Event) {
+	if e.Attempt <= 1 {
+		return
+	}
+	o.log(o.SuccessLevel, slog.LevelInfo, "succeeded after retrying", e)
+}
+
+// log writes the record with the attributes common to every event
+func (o SlogObserver) log(level slog.Leveler, defaultLevel slog.Level, msg string, e Event, attrs ...slog.Attr) {
+	logger := o.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if level == nil {
+		level = defaultLevel
+	}
+	common := []slog.Attr{
+		slog.String("operation", o.Operation),
+		slog.Uint64("attempt", uint64(e.Attempt)),
+		slog.Duration("elapsed", e.Elapsed),
+	}
+	logger.LogAttrs(context.Background(), level.Level(), msg, append(common, attrs...)...)
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 Chris Wojno
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+// documentation files (the "Software"), to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and
+// to permit persons to whom the Software is furnished to do so, subject to the following conditions: The above
+// copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+// WARRANTIES OF MERCHANTABILITY, FITNESS FOR Scaling PARTICULAR PURPOSE AND NON-INFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN
+// AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package retry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/wojnosystems/retry/retrytest"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logLines runs the retry with a SlogObserver and returns each record that was logged
+func logLines(t *testing.T, observer SlogObserver, failures int) []map[string]interface{} {
+	var buf bytes.Buffer
+	observer.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	attempt := 0
+	_ = How(ExpBase2{
+		Times:   3,
+		Scaling: 1 * time.Second,
+		Clock:   retrytest.NewAutoClock(time.Now()),
+	}.New(), observer).This(func(controller ServiceController) error {
+		attempt++
+		if attempt <= failures {
+			return errors.New("boom " + string(rune('0'+attempt)))
+		}
+		return nil
+	})
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf(`could not parse log line "%s": %v`, line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSlogObserver_GiveUp(t *testing.T) {
+	records := logLines(t, SlogObserver{Operation: "fetch"}, 3)
+	if len(records) != 3 {
+		t.Fatalf(`expected 3 records, got: %d`, len(records))
+	}
+
+	retry := records[0]
+	if retry["level"] != "WARN" || retry["operation"] != "fetch" || retry["attempt"] != float64(1) ||
+		retry["wait"] != float64(time.Second) || retry["error"] != "boom 1" {
+		t.Errorf(`unexpected retry record: %v`, retry)
+	}
+
+	giveUp := records[2]
+	if giveUp["level"] != "ERROR" || giveUp["attempt"] != float64(3) || giveUp["elapsed"] != float64(3*time.Second) ||
+		giveUp["reason"] != ErrExhausted.Error() {
+		t.Errorf(`unexpected give up record: %v`, giveUp)
+	}
+	history, ok := giveUp["errors"].(map[string]interface{})
+	if !ok {
+		t.Fatalf(`expected the errors to be logged as a group, got: %v`, giveUp["errors"])
+	}
+	if history["1"] != "boom 1" || history["2"] != "boom 2" || history["3"] != "boom 3" {
+		t.Errorf(`unexpected error history: %v`, history)
+	}
+}
+
+// TestSlogObserver_GiveUp_Dropped ensures errors are keyed by attempt number even when some were not kept
+func TestSlogObserver_GiveUp_Dropped(t *testing.T) {
+	var buf bytes.Buffer
+	observer := SlogObserver{Logger: slog.New(slog.NewJSONHandler(&buf, nil)), GiveUpLevel: slog.LevelError, RetryLevel: slog.LevelDebug}
+	attempt := 0
+	_ = How(MaxAttempts{
+		Times:     5,
+		Clock:     retrytest.NewAutoClock(time.Now()),
+		ErrorList: RingErrors{Size: 2},
+	}.New(), observer).This(func(controller ServiceController) error {
+		attempt++
+		return errors.New("boom " + string(rune('0'+attempt)))
+	})
+
+	giveUp := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &giveUp); err != nil {
+		t.Fatalf(`expected only the give up record, got "%s": %v`, buf.String(), err)
+	}
+	history, ok := giveUp["errors"].(map[string]interface{})
+	if !ok || len(history) != 2 || history["4"] != "boom 4" || history["5"] != "boom 5" {
+		t.Errorf(`expected the errors of attempts 4 and 5, got: %v`, giveUp["errors"])
+	}
+	if giveUp["dropped"] != float64(3) {
+		t.Errorf(`expected 3 dropped, got: %v`, giveUp["dropped"])
+	}
+}
+
+// TestSlogObserver_GiveUp_Refused ensures errors that did not come from an attempt are logged too
+func TestSlogObserver_GiveUp_Refused(t *testing.T) {
+	var buf bytes.Buffer
+	observer := SlogObserver{Logger: slog.New(slog.NewJSONHandler(&buf, nil)), GiveUpLevel: slog.LevelError, RetryLevel: slog.LevelDebug}
+	refused := errors.New("refused")
+	_ = How(&refusingService{Service: MaxAttempts{
+		Times: 5,
+		Clock: retrytest.NewAutoClock(time.Now()),
+	}.New(), allow: 1, err: refused}, observer).This(func(controller ServiceController) error {
+		return errors.New("boom")
+	})
+
+	giveUp := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &giveUp); err != nil {
+		t.Fatalf(`expected only the give up record, got "%s": %v`, buf.String(), err)
+	}
+	history, ok := giveUp["errors"].(map[string]interface{})
+	if !ok || len(history) != 2 || history["1"] != "boom" || history["after 1"] != refused.Error() {
+		t.Errorf(`expected the error of attempt 1 and the refusal after it, got: %v`, giveUp["errors"])
+	}
+}
+
+// refusingService lets allow attempts through, then refuses with err
+type refusingService struct {
+	Service
+	allow uint
+	err   error
+}
+
+func (s *refusingService) AllowAttempt() error {
+	if s.allow == 0 {
+		return s.err
+	}
+	s.allow--
+	return nil
+}
+
+func (s *refusingService) AttemptDone(err error) {
+}
+
+func TestSlogObserver_Levels(t *testing.T) {
+	records := logLines(t, SlogObserver{
+		RetryLevel:   slog.LevelDebug,
+		SuccessLevel: slog.LevelWarn,
+	}, 1)
+	if len(records) != 2 {
+		t.Fatalf(`expected 2 records, got: %d`, len(records))
+	}
+	if records[0]["level"] != "DEBUG" {
+		t.Errorf(`expected the retry to be logged at DEBUG, got: %v`, records[0]["level"])
+	}
+	if records[1]["level"] != "WARN" || records[1]["attempt"] != float64(2) {
+		t.Errorf(`unexpected success record: %v`, records[1])
+	}
+}
+
+func TestSlogObserver_FirstTrySuccess(t *testing.T) {
+	records := logLines(t, SlogObserver{}, 0)
+	if len(records) != 0 {
+		t.Errorf(`expected nothing to be logged, got: %v`, records)
+	}
+}